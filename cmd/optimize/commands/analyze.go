@@ -0,0 +1,79 @@
+package commands
+
+import (
+	"strings"
+
+	"github.com/harness/helm-optimize/pkg/analyze"
+	"github.com/harness/helm-optimize/pkg/dedup"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// Analyze command flags
+	analyzeFormat       string
+	analyzeKeepStrategy string
+	analyzePrefer       []string
+	analyzeValuesCheck  string
+	analyzeValueFiles   []string
+)
+
+// NewAnalyzeCmd creates the analyze subcommand
+func NewAnalyzeCmd() *cobra.Command {
+	var analyzeCmd = &cobra.Command{
+		Use:   "analyze CHART_PATH...",
+		Short: "Report optimization potential without changing the chart",
+		Long: `Analyze a chart and report its size and the duplicate dependencies
+that 'dedup' would remove, without making any changes.
+
+CHART_PATH may be a chart directory, a packaged chart (.tgz) or a local OCI
+layout directory.` + batchUsage,
+		Args: cobra.MinimumNArgs(1),
+		RunE: runAnalyze,
+	}
+
+	// Add flags specific to analyze command
+	f := analyzeCmd.Flags()
+	addBatchFlags(f)
+	f.StringVar(&analyzeFormat, "format", analyze.FormatText, "Output format: text|json")
+	f.StringVar(&analyzeKeepStrategy, "keep-strategy", dedup.StrategyFirst, "Which duplicate copy to keep: "+strings.Join(dedup.KnownStrategies, "|"))
+	f.StringSliceVar(&analyzePrefer, "prefer", nil, "Subchart path globs to keep with --keep-strategy explicit, in order of preference")
+
+	f.StringVar(&analyzeValuesCheck, "values-check", dedup.ValuesCheckWarn, "How to handle duplicates receiving different values: "+strings.Join(dedup.KnownValuesChecks, "|"))
+	f.StringSliceVarP(&analyzeValueFiles, "values", "f", nil, "Values files applied on top of the chart values for the values check (can be repeated)")
+
+	return analyzeCmd
+}
+
+// runAnalyze implements the analyze command logic
+func runAnalyze(cmd *cobra.Command, args []string) error {
+	return runBatch(args, func(source string) error {
+		return runAnalyzeChart(cmd, source)
+	})
+}
+
+// runAnalyzeChart runs the analyze command against a single chart source
+func runAnalyzeChart(cmd *cobra.Command, source string) error {
+	ws, err := openWorkspace(source)
+	if err != nil {
+		return err
+	}
+	defer ws.Close()
+
+	cfg, err := loadConfig(ws.ChartPath)
+	if err != nil {
+		return err
+	}
+
+	return analyze.Run(analyze.Options{
+		ChartPath:    ws.ChartPath,
+		Source:       source,
+		Format:       analyzeFormat,
+		KeepStrategy: override(cmd, "keep-strategy", analyzeKeepStrategy, cfg.Dedup.KeepStrategy),
+		Prefer:       override(cmd, "prefer", analyzePrefer, cfg.Dedup.Prefer),
+		ValuesCheck:  override(cmd, "values-check", analyzeValuesCheck, cfg.Dedup.ValuesCheck),
+		ValueFiles:   override(cmd, "values", analyzeValueFiles, cfg.Dedup.Values),
+		Include:      cfg.Charts.Include,
+		Exclude:      cfg.Charts.Exclude,
+		NeverDelete:  cfg.NeverDelete,
+	})
+}
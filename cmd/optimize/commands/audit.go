@@ -0,0 +1,121 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/harness/helm-optimize/pkg/audit"
+	"github.com/harness/helm-optimize/pkg/common"
+	"github.com/harness/helm-optimize/pkg/release"
+	"github.com/spf13/cobra"
+)
+
+// Exit codes of the audit-size command
+const (
+	// auditExitWarning reports large or binary files
+	auditExitWarning = 2
+	// auditExitError reports a release exceeding the storage limit
+	auditExitError = 3
+)
+
+// Values accepted by --fail-on
+const (
+	failOnWarning = "warning"
+	failOnError   = "error"
+	failOnNone    = "none"
+)
+
+var (
+	// Audit command flags
+	auditFormat         string
+	auditMaxFileSize    string
+	auditMaxReleaseSize string
+	auditFailOn         string
+	auditValueFiles     []string
+)
+
+// NewAuditCmd creates the audit-size subcommand
+func NewAuditCmd() *cobra.Command {
+	var auditCmd = &cobra.Command{
+		Use:   "audit-size CHART_PATH",
+		Short: "Report large files, binary files and oversized releases",
+		Long: `Inspect the files Helm packages for the chart and its subcharts (honouring
+.helmignore) and report:
+
+  - files larger than --max-file-size
+  - binary files, which are stored in every release revision
+  - a release record exceeding Helm's storage limit: the chart is rendered and
+    encoded like Helm's Secret and ConfigMap drivers (JSON, gzip, base64)
+
+Exit codes, for CI gating:
+  0  no findings at or above the --fail-on level
+  1  the audit could not be performed
+  2  large or binary files were found (warnings)
+  3  the release record exceeds --max-release-size (error)
+
+Sizes accept the suffixes K, M, Ki and Mi.
+
+CHART_PATH may be a chart directory, a packaged chart (.tgz) or a local OCI
+layout directory.`,
+		Args: cobra.ExactArgs(1),
+		RunE: runAudit,
+	}
+
+	// Add flags specific to audit-size command
+	f := auditCmd.Flags()
+	f.StringVar(&auditFormat, "format", audit.FormatText, "Output format: text|json")
+	f.StringVar(&auditMaxFileSize, "max-file-size", "100Ki", "Report files larger than this size")
+	f.StringVar(&auditMaxReleaseSize, "max-release-size", "1Mi", "Maximum encoded release size Helm storage accepts")
+	f.StringVar(&auditFailOn, "fail-on", failOnWarning, "Lowest severity that makes the command fail: warning|error|none")
+	f.StringSliceVarP(&auditValueFiles, "values", "f", nil, "Values files used to render the release for the size estimate (can be repeated)")
+
+	return auditCmd
+}
+
+// runAudit implements the audit-size command logic
+func runAudit(cmd *cobra.Command, args []string) error {
+	if auditFailOn != failOnWarning && auditFailOn != failOnError && auditFailOn != failOnNone {
+		return usageErrorf("unknown --fail-on level '%s' (known: %s, %s, %s)", auditFailOn, failOnWarning, failOnError, failOnNone)
+	}
+	maxFileSize, err := common.ParseSize(auditMaxFileSize)
+	if err != nil {
+		return usageErrorf("invalid --max-file-size: %w", err)
+	}
+	maxReleaseSize, err := common.ParseSize(auditMaxReleaseSize)
+	if err != nil {
+		return usageErrorf("invalid --max-release-size: %w", err)
+	}
+	if maxReleaseSize == 0 {
+		maxReleaseSize = release.StorageLimit
+	}
+
+	ws, err := openWorkspace(args[0])
+	if err != nil {
+		return err
+	}
+	defer ws.Close()
+
+	report, err := audit.Audit(audit.Options{
+		ChartPath:      ws.ChartPath,
+		MaxFileSize:    maxFileSize,
+		MaxReleaseSize: maxReleaseSize,
+		ValueFiles:     auditValueFiles,
+		Verbose:        IsVerbose(),
+	})
+	if err != nil {
+		return err
+	}
+	if err := report.Write(os.Stdout, auditFormat); err != nil {
+		return err
+	}
+
+	switch {
+	case auditFailOn == failOnNone:
+		return nil
+	case report.Has(audit.SeverityError):
+		return &ExitError{Code: auditExitError, Err: fmt.Errorf("release record of '%s' exceeds the storage limit", args[0])}
+	case auditFailOn == failOnWarning && report.Has(audit.SeverityWarning):
+		return &ExitError{Code: auditExitWarning, Err: fmt.Errorf("size audit of '%s' found large or binary files", args[0])}
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+
+	"github.com/harness/helm-optimize/pkg/batch"
+	"github.com/spf13/pflag"
+)
+
+var (
+	// Batch flags shared by the commands accepting several charts
+	batchParallel int
+)
+
+// batchUsage documents the multi-chart arguments in command help
+const batchUsage = `
+
+Several CHART_PATH arguments may be given. An argument ending in '/...' (for
+example ./charts/...) is searched recursively for chart roots: directories
+with a Chart.yaml that are not nested inside another chart.
+The charts are processed in parallel and a combined summary is printed; the
+command fails if any chart failed.`
+
+// addBatchFlags adds the flags controlling multi-chart runs
+func addBatchFlags(f *pflag.FlagSet) {
+	f.IntVar(&batchParallel, "parallel", runtime.NumCPU(), "Number of charts processed concurrently when several charts are given")
+}
+
+// isBatch reports whether the arguments name several charts
+func isBatch(args []string) bool {
+	return len(args) > 1 || batch.IsPattern(args[0])
+}
+
+// batchReportDir prepares the directory receiving one report per chart of a
+// multi-chart run, where the --report flag names a directory
+func batchReportDir(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create report directory: %w", err)
+	}
+	return nil
+}
+
+// runBatch runs fn for every chart named by the arguments. A single plain
+// chart path is processed directly, without a summary.
+func runBatch(args []string, fn func(source string) error) error {
+	if !isBatch(args) {
+		return fn(args[0])
+	}
+
+	charts, err := batch.Discover(args)
+	if err != nil {
+		return err
+	}
+	Logger().Debug("processing charts", "charts", len(charts), "workers", batchParallel)
+
+	results := batch.Run(charts, batchParallel, fn)
+	batch.PrintSummary(os.Stdout, results)
+
+	return batch.Err(results)
+}
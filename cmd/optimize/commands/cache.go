@@ -0,0 +1,102 @@
+package commands
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/harness/helm-optimize/pkg/cache"
+	"github.com/harness/helm-optimize/pkg/common"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// Cache prune command flags
+	cachePruneOlderThan time.Duration
+	cachePruneAll       bool
+	cachePruneDryRun    bool
+)
+
+// NewCacheCmd creates the cache subcommand
+func NewCacheCmd() *cobra.Command {
+	var cacheCmd = &cobra.Command{
+		Use:   "cache",
+		Short: "Manage the analysis cache",
+		Long: `Manage the analysis cache.
+
+dedup caches the analysis of every subchart under $HELM_CACHE_HOME/optimize,
+keyed by the digest of the subchart content, so that repeated runs only
+analyze the subcharts that changed. Pass --no-cache to dedup to bypass it.`,
+	}
+
+	cacheCmd.AddCommand(newCachePruneCmd())
+
+	return cacheCmd
+}
+
+// newCachePruneCmd creates the cache prune subcommand
+func newCachePruneCmd() *cobra.Command {
+	pruneCmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Remove cache entries that were not used recently",
+		Long: `Remove the cache entries not used within --older-than, or every entry with
+--all. Digests of files that changed or no longer exist are dropped as well.`,
+		Args: cobra.NoArgs,
+		RunE: runCachePrune,
+	}
+
+	f := pruneCmd.Flags()
+	f.DurationVar(&cachePruneOlderThan, "older-than", 30*24*time.Hour, "Remove entries not used for this long")
+	f.BoolVar(&cachePruneAll, "all", false, "Remove every entry")
+	f.BoolVar(&cachePruneDryRun, "dry-run", false, "Show what would be removed without removing it")
+
+	return pruneCmd
+}
+
+// runCachePrune implements the cache prune command logic
+func runCachePrune(cmd *cobra.Command, args []string) error {
+	maxAge := cachePruneOlderThan
+	if cachePruneAll {
+		maxAge = 0
+	} else if maxAge <= 0 {
+		return usageErrorf("--older-than must be positive, use --all to remove every entry")
+	}
+
+	dir := cache.DefaultDir()
+	result, err := cache.Prune(dir, maxAge, cachePruneDryRun)
+	if err != nil {
+		return fmt.Errorf("failed to prune cache %s: %w", dir, err)
+	}
+
+	verb := "Removed"
+	if cachePruneDryRun {
+		verb = "Would remove"
+	}
+	fmt.Printf("%s %d cache entries and %d file digests from %s (%s)\n", verb, result.Entries, result.Files, dir, common.FormatSize(result.Freed))
+	return nil
+}
+
+// openCache opens the analysis cache unless --no-cache is given. A cache that
+// cannot be opened only disables caching.
+func openCache() *cache.Cache {
+	if noCache {
+		return nil
+	}
+	c, err := cache.Open(cache.DefaultDir())
+	if err != nil {
+		Logger().Warn("analysis cache disabled", "error", err)
+		return nil
+	}
+	return c
+}
+
+// closeCache saves the cache and logs its use at debug level
+func closeCache(c *cache.Cache) {
+	if c == nil {
+		return
+	}
+	hits, misses := c.Stats()
+	Logger().Debug("analysis cache used", "dir", c.Dir(), "hits", hits, "misses", misses)
+	if err := c.Close(); err != nil {
+		Logger().Warn("failed to save analysis cache", "error", err)
+	}
+}
@@ -0,0 +1,15 @@
+package commands
+
+import (
+	"github.com/spf13/pflag"
+)
+
+var (
+	// Check flag shared by the commands that optimize charts
+	checkMode bool
+)
+
+// addCheckFlag adds the flag running an optimization in check mode
+func addCheckFlag(f *pflag.FlagSet) {
+	f.BoolVar(&checkMode, "check", false, "Only list what would change and exit with code 10 if anything would (implies --dry-run), like 'gofmt -l' for CI")
+}
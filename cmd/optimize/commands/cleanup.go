@@ -9,42 +9,91 @@ var (
 	// Cleanup command flags
 	cleanupDryRun      bool
 	cleanupShowDeleted bool
+	cleanupKeep        []string
+	cleanupExclude     []string
+	cleanupOutputDir   string
 )
 
 // NewCleanupCmd creates the cleanup subcommand
 func NewCleanupCmd() *cobra.Command {
 	var cleanupCmd = &cobra.Command{
-		Use:   "cleanup [CHART_PATH]",
+		Use:   "cleanup CHART_PATH...",
 		Short: "Remove unnecessary chart directories",
 		Long: `Remove unnecessary directories created during 'helm dep up'.
 		
 This command performs a depth-first search on Helm charts, runs 'helm dep up'
 at the bottom-most level, and removes original directories for dependencies 
-with 'repository: file:' format after the dependency charts are created.`,
-		Args: cobra.ExactArgs(1),
-		RunE: runCleanup,
+with 'repository: file:' format after the dependency charts are created.
+
+CHART_PATH may be a chart directory, a packaged chart (.tgz) or a local OCI
+layout directory. Archives and OCI layouts are extracted to a temporary
+workspace and the cleaned chart is written as a new archive to --output.` + batchUsage,
+		Args:    cobra.MinimumNArgs(1),
+		PreRunE: loadSigner,
+		RunE:    runCleanup,
 	}
 
 	// Add flags specific to cleanup command
 	f := cleanupCmd.Flags()
+	addBatchFlags(f)
+	addSignFlags(f)
+	addCheckFlag(f)
+	addSymlinksFlag(f)
+	addLintFlags(f)
+	f.StringVarP(&cleanupOutputDir, "output", "o", "", "Output directory for the new archive when CHART_PATH is an archive (default: directory containing CHART_PATH)")
 	f.BoolVar(&cleanupDryRun, "dry-run", false, "Simulate cleanup without making changes")
 	f.BoolVar(&cleanupShowDeleted, "show-deleted", false, "Show paths that would be deleted")
+	f.StringSliceVar(&cleanupKeep, "keep", nil, "Never delete directories matching these path globs or chart names (added to the configuration file)")
+	f.StringSliceVar(&cleanupExclude, "exclude", nil, "Skip charts matching these path globs or chart names (added to the configuration file)")
 
 	return cleanupCmd
 }
 
 // runCleanup implements the cleanup command logic
 func runCleanup(cmd *cobra.Command, args []string) error {
-	chartPath := args[0]
+	return runBatch(args, func(source string) error {
+		return runCleanupChart(cmd, source)
+	})
+}
+
+// runCleanupChart runs the cleanup command against a single chart source
+func runCleanupChart(cmd *cobra.Command, source string) error {
+	ws, err := openWorkspace(source)
+	if err != nil {
+		return err
+	}
+	defer ws.Close()
+	chartPath := ws.ChartPath
+
+	// Load the chart configuration; command line flags take precedence
+	cfg, err := loadConfig(chartPath)
+	if err != nil {
+		return err
+	}
 
 	// Create cleanup options
 	opts := cleanup.Options{
 		ChartPath:   chartPath,
-		DryRun:      cleanupDryRun,
-		ShowDeleted: cleanupShowDeleted,
-		Verbose:     IsVerbose(),
+		DryRun:      override(cmd, "dry-run", cleanupDryRun, cfg.Cleanup.DryRun) || checkMode,
+		ShowDeleted: override(cmd, "show-deleted", cleanupShowDeleted, cfg.Cleanup.ShowDeleted),
+		Check:       checkMode,
+		Logger:      Logger(),
+		Include:     cfg.Charts.Include,
+		Exclude:     append(cfg.Charts.Exclude, cleanupExclude...),
+		NeverDelete: append(cfg.NeverDelete, cleanupKeep...),
+		Symlinks:    override(cmd, "symlinks", symlinks, cfg.Charts.Symlinks),
 	}
 
 	// Run the cleanup
-	return cleanup.Run(opts)
+	if err := withLint(chartPath, opts.DryRun, func() error { return cleanup.Run(opts) }); err != nil {
+		return err
+	}
+	if cfg.Verify.Load && !opts.DryRun {
+		if err := verifyLoad(chartPath); err != nil {
+			return err
+		}
+		Logger().Info("verification passed, optimized chart loads successfully", "chart", chartPath)
+	}
+
+	return repackage(ws, cleanupOutputDir, opts.DryRun)
 }
@@ -0,0 +1,78 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/harness/helm-optimize/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+// NewConfigCmd creates the config subcommand
+func NewConfigCmd() *cobra.Command {
+	var configCmd = &cobra.Command{
+		Use:   "config",
+		Short: "Inspect the helm-optimize configuration file",
+		Long: `Inspect the helm-optimize configuration file.
+
+The configuration file is discovered at the chart root as '.helm-optimize.yaml'
+or passed explicitly with the global --config flag.`,
+	}
+
+	configCmd.AddCommand(newConfigValidateCmd())
+
+	return configCmd
+}
+
+// newConfigValidateCmd creates the config validate subcommand
+func newConfigValidateCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "validate [CHART_PATH]",
+		Short: "Validate the configuration file",
+		Long: `Validate the configuration file of a chart.
+
+Without arguments the file given by --config is validated; otherwise the
+configuration is discovered at CHART_PATH.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: runConfigValidate,
+	}
+}
+
+// runConfigValidate implements the config validate command logic
+func runConfigValidate(cmd *cobra.Command, args []string) error {
+	chartPath := "."
+	if len(args) > 0 {
+		chartPath = args[0]
+	}
+
+	cfg, err := loadConfig(chartPath)
+	if err != nil {
+		return err
+	}
+	if cfg.Path() == "" {
+		return fmt.Errorf("no configuration file found at '%s'", chartPath)
+	}
+
+	fmt.Printf("Configuration file '%s' is valid.\n", cfg.Path())
+	return nil
+}
+
+// loadConfig discovers, parses and validates the configuration for a chart
+func loadConfig(chartPath string) (*config.Config, error) {
+	cfg, err := config.Discover(chartPath, configFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration file '%s':\n%w", cfg.Path(), err)
+	}
+	return cfg, nil
+}
+
+// override returns the flag value when the flag was set on the command line,
+// otherwise the value from the configuration file
+func override[T any](cmd *cobra.Command, name string, flagValue, configValue T) T {
+	if cmd.Flags().Changed(name) {
+		return flagValue
+	}
+	return configValue
+}
@@ -0,0 +1,94 @@
+package commands
+
+import (
+	"github.com/harness/helm-optimize/pkg/crds"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// Extract CRDs command flags
+	crdsName        string
+	crdsChartOutput string
+	crdsDir         string
+	crdsOutputDir   string
+	crdsDryRun      bool
+	crdsShowDeleted bool
+	crdsExclude     []string
+)
+
+// NewExtractCRDsCmd creates the extract-crds subcommand
+func NewExtractCRDsCmd() *cobra.Command {
+	var crdsCmd = &cobra.Command{
+		Use:   "extract-crds CHART_PATH",
+		Short: "Move the CRDs of a chart tree into a standalone CRD chart",
+		Long: `Collect the CustomResourceDefinitions shipped in the crds/ and templates/
+directories of the chart and all of its unpacked subcharts, write them into a
+standalone CRD chart and remove them from the charts that shipped them.
+
+Copies of a CRD are identified by group and kind. Identical copies (same
+versions and content) are extracted once. When subcharts ship different
+versions or contents of the same CRD, the conflict is reported and every copy
+is left in place. CRDs in templates that contain template actions cannot be
+extracted safely and are reported as skipped.
+
+CHART_PATH may be a chart directory, a packaged chart (.tgz) or a local OCI
+layout directory.`,
+		Args:    cobra.ExactArgs(1),
+		PreRunE: loadSigner,
+		RunE:    runExtractCRDs,
+	}
+
+	// Add flags specific to extract-crds command
+	f := crdsCmd.Flags()
+	addSignFlags(f)
+	addLintFlags(f)
+	addCheckFlag(f)
+	f.StringVar(&crdsName, "name", "", "Name of the CRD chart (default: <chart name>-crds)")
+	f.StringVar(&crdsChartOutput, "crd-output", "", "Directory the CRD chart is created in (default: directory containing CHART_PATH)")
+	f.StringVar(&crdsDir, "crd-dir", crds.DirTemplates, "Directory of the CRD chart holding the CRDs: templates (upgraded by helm upgrade) or crds (install only)")
+	f.StringVarP(&crdsOutputDir, "output", "o", "", "Output directory for the new archive when CHART_PATH is an archive (default: directory containing CHART_PATH)")
+	f.BoolVar(&crdsDryRun, "dry-run", false, "Report the CRDs without changing anything")
+	f.BoolVar(&crdsShowDeleted, "show-deleted", false, "Show files the CRDs are removed from")
+	f.StringSliceVar(&crdsExclude, "exclude", nil, "Skip subcharts matching these path globs or chart names (added to the configuration file)")
+
+	return crdsCmd
+}
+
+// runExtractCRDs implements the extract-crds command logic
+func runExtractCRDs(cmd *cobra.Command, args []string) error {
+	source := args[0]
+	ws, err := openWorkspace(source)
+	if err != nil {
+		return err
+	}
+	defer ws.Close()
+
+	cfg, err := loadConfig(ws.ChartPath)
+	if err != nil {
+		return err
+	}
+
+	// Extracted charts default to the directory of the archive, not the temporary workspace
+	chartOutput := crdsChartOutput
+	if chartOutput == "" && ws.IsTemporary() {
+		chartOutput = ws.DefaultOutputDir()
+	}
+
+	opts := crds.Options{
+		ChartPath:   ws.ChartPath,
+		OutputDir:   chartOutput,
+		Name:        crdsName,
+		Dir:         crdsDir,
+		DryRun:      crdsDryRun || checkMode,
+		ShowDeleted: crdsShowDeleted,
+		Verbose:     IsVerbose(),
+		Check:       checkMode,
+		Exclude:     append(cfg.Charts.Exclude, crdsExclude...),
+	}
+
+	if err := withLint(ws.ChartPath, opts.DryRun, func() error { return crds.Run(opts) }); err != nil {
+		return err
+	}
+
+	return repackage(ws, crdsOutputDir, opts.DryRun)
+}
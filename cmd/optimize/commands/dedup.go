@@ -1,55 +1,148 @@
 package commands
 
 import (
-	"github.com/spf13/cobra"
+	"strings"
+
+	"github.com/harness/helm-optimize/pkg/batch"
+	"github.com/harness/helm-optimize/pkg/cache"
 	"github.com/harness/helm-optimize/pkg/dedup"
+	"github.com/spf13/cobra"
 )
 
 var (
 	// Dedup command flags
-	outputDir   string
-	package_    bool
-	dryRun      bool
-	showDeleted bool
+	outputDir    string
+	package_     bool
+	dryRun       bool
+	showDeleted  bool
+	keep         []string
+	exclude      []string
+	keepStrategy string
+	prefer       []string
+	valuesCheck  string
+	valueFiles   []string
+	push         string
+	plainHTTP    bool
+	reportFile   string
+	noCache      bool
 )
 
 // NewDedupCmd creates the dedup subcommand
 func NewDedupCmd() *cobra.Command {
 	var dedupCmd = &cobra.Command{
-		Use:   "dedup [CHART_PATH]",
+		Use:   "dedup CHART_PATH...",
 		Short: "Deduplicate dependency charts",
 		Long: `Deduplicate dependency charts to reduce the size of Helm packages.
 
 This command analyzes a Helm chart and its dependencies, identifying and removing
-duplicate subchart references while maintaining all required functionality.`,
-		Args: cobra.ExactArgs(1),
-		RunE: runDedup,
+duplicate subchart references while maintaining all required functionality.
+
+CHART_PATH may be a chart directory, a packaged chart (.tgz) or a local OCI
+layout directory. Archives and OCI layouts are extracted to a temporary
+workspace and the optimized chart is written as a new archive to --output.` + batchUsage,
+		Args:    cobra.MinimumNArgs(1),
+		PreRunE: loadSigner,
+		RunE:    runDedup,
 	}
 
 	// Add flags specific to dedup command
 	f := dedupCmd.Flags()
-	f.StringVarP(&outputDir, "output", "o", "", "Output directory for deduplicated chart (default: input directory)")
+	addBatchFlags(f)
+	addSignFlags(f)
+	addCheckFlag(f)
+	addSymlinksFlag(f)
+	addLintFlags(f)
+	f.StringVarP(&outputDir, "output", "o", "", "Output directory for the packaged chart (default: directory containing CHART_PATH)")
 	f.BoolVarP(&package_, "package", "p", false, "Package chart after deduplication")
 	f.BoolVar(&dryRun, "dry-run", false, "Simulate deduplication without making changes")
 	f.BoolVar(&showDeleted, "show-deleted", false, "Show paths that would be deleted")
+	f.StringSliceVar(&keep, "keep", nil, "Never delete subcharts matching these path globs or chart names (added to the configuration file)")
+	f.StringVar(&keepStrategy, "keep-strategy", dedup.StrategyFirst, "Which duplicate copy to keep: "+strings.Join(dedup.KnownStrategies, "|"))
+	f.StringSliceVar(&prefer, "prefer", nil, "Subchart path globs to keep with --keep-strategy explicit, in order of preference")
+	f.StringVar(&valuesCheck, "values-check", dedup.ValuesCheckWarn, "How to handle duplicates receiving different values: "+strings.Join(dedup.KnownValuesChecks, "|"))
+	f.StringSliceVarP(&valueFiles, "values", "f", nil, "Values files applied on top of the chart values for the values check (can be repeated)")
+	f.StringSliceVar(&exclude, "exclude", nil, "Skip subcharts matching these path globs or chart names (added to the configuration file)")
+	f.StringVar(&push, "push", "", "Push the packaged chart to this OCI repository, e.g. oci://host/repo (implies --package)")
+	f.BoolVar(&plainHTTP, "plain-http", false, "Use HTTP instead of HTTPS for the --push registry")
+	f.StringVar(&reportFile, "report", "", "Write a JSON report of the run, including the pushed digest, to this file (a directory with one report per chart when several charts are given)")
+	f.BoolVar(&noCache, "no-cache", false, "Analyze every subchart again instead of reusing the results cached by previous runs")
 
 	return dedupCmd
 }
 
 // runDedup implements the dedup command logic
 func runDedup(cmd *cobra.Command, args []string) error {
-	chartPath := args[0]
-	
+	analysisCache := openCache()
+	defer closeCache(analysisCache)
+
+	// Every chart of a multi-chart run gets its own report in the --report directory
+	report := func(string) string { return reportFile }
+	if reportFile != "" && isBatch(args) {
+		if err := batchReportDir(reportFile); err != nil {
+			return err
+		}
+		report = func(source string) string { return batch.ReportPath(reportFile, source) }
+	}
+
+	return runBatch(args, func(source string) error {
+		return runDedupChart(cmd, source, report(source), analysisCache)
+	})
+}
+
+// runDedupChart runs the dedup command against a single chart source
+func runDedupChart(cmd *cobra.Command, source, reportFile string, analysisCache *cache.Cache) error {
+	ws, err := openWorkspace(source)
+	if err != nil {
+		return err
+	}
+	defer ws.Close()
+	chartPath := ws.ChartPath
+
+	// Load the chart configuration; command line flags take precedence
+	cfg, err := loadConfig(chartPath)
+	if err != nil {
+		return err
+	}
+
 	// Create deduplicator options
 	opts := dedup.Options{
-		ChartPath:   chartPath,
-		OutputDir:   outputDir,
-		Package:     package_,
-		DryRun:      dryRun,
-		ShowDeleted: showDeleted,
-		Verbose:     IsVerbose(),
+		ChartPath:    chartPath,
+		OutputDir:    override(cmd, "output", outputDir, cfg.Dedup.Output),
+		Package:      override(cmd, "package", package_, cfg.Dedup.Package),
+		DryRun:       override(cmd, "dry-run", dryRun, cfg.Dedup.DryRun) || checkMode,
+		Check:        checkMode,
+		ShowDeleted:  override(cmd, "show-deleted", showDeleted, cfg.Dedup.ShowDeleted),
+		Logger:       Logger(),
+		Include:      cfg.Charts.Include,
+		Exclude:      append(cfg.Charts.Exclude, exclude...),
+		NeverDelete:  append(cfg.NeverDelete, keep...),
+		Symlinks:     override(cmd, "symlinks", symlinks, cfg.Charts.Symlinks),
+		KeepStrategy: override(cmd, "keep-strategy", keepStrategy, cfg.Dedup.KeepStrategy),
+		Prefer:       override(cmd, "prefer", prefer, cfg.Dedup.Prefer),
+		ValuesCheck:  override(cmd, "values-check", valuesCheck, cfg.Dedup.ValuesCheck),
+		ValueFiles:   override(cmd, "values", valueFiles, cfg.Dedup.Values),
+		Push:         override(cmd, "push", push, cfg.Dedup.Push),
+		PlainHTTP:    override(cmd, "plain-http", plainHTTP, cfg.Dedup.PlainHTTP),
+		ReportFile:   reportFile,
+		Signer:       signer,
+		Cache:        analysisCache,
+		Lint:         lintOptions(),
+		VerifyLoad:   cfg.Verify.Load,
+	}
+
+	// Archives are always repackaged, next to the source unless --output is given
+	if ws.IsTemporary() {
+		opts.Package = true
+		if opts.OutputDir == "" {
+			opts.OutputDir = ws.DefaultOutputDir()
+		}
+		if !opts.DryRun {
+			if err := ws.CheckOutput(opts.OutputDir); err != nil {
+				return err
+			}
+		}
 	}
-	
+
 	// Run the deduplication
 	return dedup.Run(opts)
 }
@@ -0,0 +1,71 @@
+package commands
+
+import (
+	"strings"
+
+	"github.com/harness/helm-optimize/pkg/diff"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// Diff command flags
+	diffRender     bool
+	diffValueFiles []string
+	diffFormat     string
+)
+
+// NewDiffCmd creates the diff subcommand
+func NewDiffCmd() *cobra.Command {
+	var diffCmd = &cobra.Command{
+		Use:   "diff BEFORE AFTER",
+		Short: "Compare two charts or two archives",
+		Long: `Compare an original chart with its optimized version.
+
+The charts are compared structurally: subcharts added, removed or replaced by
+another version (found with the same traversal as dedup), changed Chart.yaml
+and Chart.lock fields, and the byte delta of every file that differs. Files of
+added or removed subcharts are summarized by the subchart entry.
+
+With --render both charts are rendered with the Helm engine and the objects
+are compared by apiVersion, kind and name, showing a unified diff of the
+objects that changed.
+
+BEFORE and AFTER may be chart directories, packaged charts (.tgz) or local OCI
+layout directories.`,
+		Args: cobra.ExactArgs(2),
+		RunE: runDiff,
+	}
+
+	// Add flags specific to diff command
+	f := diffCmd.Flags()
+	f.BoolVar(&diffRender, "render", false, "Also compare the rendered manifests")
+	f.StringSliceVarP(&diffValueFiles, "values", "f", nil, "Values files used to render both charts with --render (can be repeated)")
+	f.StringVar(&diffFormat, "format", diff.FormatText, "Output format: "+strings.Join(diff.KnownFormats, "|"))
+
+	return diffCmd
+}
+
+// runDiff implements the diff command logic
+func runDiff(cmd *cobra.Command, args []string) error {
+	before, err := openWorkspace(args[0])
+	if err != nil {
+		return err
+	}
+	defer before.Close()
+
+	after, err := openWorkspace(args[1])
+	if err != nil {
+		return err
+	}
+	defer after.Close()
+
+	return diff.Run(diff.Options{
+		Before:     before.ChartPath,
+		After:      after.ChartPath,
+		BeforeName: args[0],
+		AfterName:  args[1],
+		Render:     diffRender,
+		ValueFiles: diffValueFiles,
+		Format:     diffFormat,
+	})
+}
@@ -0,0 +1,94 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/harness/helm-optimize/pkg/common"
+)
+
+// Exit codes of the plugin. audit-size additionally exits with 2 when it
+// finds warnings and 3 when the release exceeds the storage limit.
+const (
+	// ExitOK reports success, including a chart with nothing to optimize
+	ExitOK = 0
+	// ExitFailure reports an unexpected failure
+	ExitFailure = 1
+	// ExitUsage reports invalid arguments or flags
+	ExitUsage = 4
+	// ExitChartNotFound reports a chart path without a chart
+	ExitChartNotFound = 5
+	// ExitInvalidChart reports a Chart.yaml or chart Helm cannot read
+	ExitInvalidChart = 6
+	// ExitUnsafePath reports an optimization refusing to touch a path outside the chart
+	ExitUnsafePath = 7
+	// ExitVerificationFailed reports an optimized chart or archive failing verification
+	ExitVerificationFailed = 8
+	// ExitPartialFailure reports a multi-chart run in which only some charts failed
+	ExitPartialFailure = 9
+	// ExitNotOptimized reports, with --check, a chart an optimization would change
+	ExitNotOptimized = 10
+)
+
+// exitCodes maps the error categories to exit codes, most specific first
+var exitCodes = []struct {
+	err  error
+	code int
+}{
+	{common.ErrPartialFailure, ExitPartialFailure},
+	{common.ErrVerificationFailed, ExitVerificationFailed},
+	{common.ErrUnsafePath, ExitUnsafePath},
+	{common.ErrInvalidChart, ExitInvalidChart},
+	{common.ErrChartNotFound, ExitChartNotFound},
+	{common.ErrNotOptimized, ExitNotOptimized},
+}
+
+// commandStarted is set once the arguments and flags were accepted and the
+// command runs; errors before that are usage errors
+var commandStarted bool
+
+// ExitError ends the program with a specific exit code. Commands return it
+// when the exit code carries meaning, e.g. for CI gating.
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+// Error returns the message of the wrapped error
+func (e *ExitError) Error() string {
+	if e.Err == nil {
+		return ""
+	}
+	return e.Err.Error()
+}
+
+// Unwrap returns the wrapped error
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// usageErrorf reports an invalid flag value detected by a command
+func usageErrorf(format string, args ...interface{}) error {
+	return &ExitError{Code: ExitUsage, Err: fmt.Errorf(format, args...)}
+}
+
+// ExitCode returns the exit code for an error returned by the root command
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.Code
+	}
+	for _, c := range exitCodes {
+		if errors.Is(err, c.err) {
+			return c.code
+		}
+	}
+	if !commandStarted {
+		return ExitUsage
+	}
+	return ExitFailure
+}
@@ -0,0 +1,84 @@
+package commands
+
+import (
+	"github.com/harness/helm-optimize/pkg/helpers"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// Helpers command flags
+	helpersConsolidate bool
+	helpersLibrary     string
+	helpersValueFiles  []string
+	helpersExclude     []string
+	helpersOutputDir   string
+)
+
+// NewHelpersCmd creates the helpers subcommand
+func NewHelpersCmd() *cobra.Command {
+	var helpersCmd = &cobra.Command{
+		Use:   "helpers CHART_PATH",
+		Short: "Find named templates duplicated across subcharts",
+		Long: `Parse every 'define' block in the templates of the chart and its unpacked
+subcharts, group the named templates whose bodies are identical (even when
+their names differ) and report the bytes consolidating them would save.
+
+With --consolidate, each group is moved once into a shared library chart in
+the root chart's charts/ directory and the original definitions become thin
+wrappers including it. The consolidation is always rendered first on a copy of
+the chart and is only applied when the rendered manifests are identical.
+With --check, the consolidation is verified but not applied, and the files it
+would rewrite are listed.
+
+CHART_PATH may be a chart directory, a packaged chart (.tgz) or a local OCI
+layout directory.`,
+		Args:    cobra.ExactArgs(1),
+		PreRunE: loadSigner,
+		RunE:    runHelpers,
+	}
+
+	// Add flags specific to helpers command
+	f := helpersCmd.Flags()
+	addSignFlags(f)
+	addLintFlags(f)
+	addCheckFlag(f)
+	f.BoolVar(&helpersConsolidate, "consolidate", false, "Move duplicated helpers into a shared library chart")
+	f.StringVar(&helpersLibrary, "library", "", "Name of the shared library chart (default: <chart name>-helpers)")
+	f.StringSliceVarP(&helpersValueFiles, "values", "f", nil, "Values files used to render the chart for the equivalence check (can be repeated)")
+	f.StringSliceVar(&helpersExclude, "exclude", nil, "Skip subcharts matching these path globs or chart names (added to the configuration file)")
+	f.StringVarP(&helpersOutputDir, "output", "o", "", "Output directory for the new archive when CHART_PATH is an archive (default: directory containing CHART_PATH)")
+
+	return helpersCmd
+}
+
+// runHelpers implements the helpers command logic
+func runHelpers(cmd *cobra.Command, args []string) error {
+	ws, err := openWorkspace(args[0])
+	if err != nil {
+		return err
+	}
+	defer ws.Close()
+
+	cfg, err := loadConfig(ws.ChartPath)
+	if err != nil {
+		return err
+	}
+
+	opts := helpers.Options{
+		ChartPath:   ws.ChartPath,
+		Consolidate: helpersConsolidate,
+		Check:       checkMode,
+		Library:     helpersLibrary,
+		ValueFiles:  helpersValueFiles,
+		Verbose:     IsVerbose(),
+		Exclude:     append(cfg.Charts.Exclude, helpersExclude...),
+	}
+
+	// Only an applied consolidation changes the chart
+	dryRun := !opts.Consolidate || opts.Check
+	if err := withLint(ws.ChartPath, dryRun, func() error { return helpers.Run(opts) }); err != nil {
+		return err
+	}
+
+	return repackage(ws, helpersOutputDir, dryRun)
+}
@@ -0,0 +1,63 @@
+package commands
+
+import (
+	"strings"
+
+	"github.com/harness/helm-optimize/pkg/images"
+	"github.com/harness/helm-optimize/pkg/render"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// Images command flags
+	imagesFormat      string
+	imagesValueFiles  []string
+	imagesReleaseName string
+	imagesNamespace   string
+)
+
+// NewImagesCmd creates the images subcommand
+func NewImagesCmd() *cobra.Command {
+	var imagesCmd = &cobra.Command{
+		Use:   "images CHART_PATH",
+		Short: "List the container images a chart deploys",
+		Long: `Render the chart tree with the Helm engine and list every container image
+referenced by the pod specs of Pods, Deployments, StatefulSets, DaemonSets,
+ReplicaSets, Jobs and CronJobs, including init containers.
+
+Each image is listed once together with the subcharts and workloads using it.
+The list can be written as text, JSON or a CycloneDX 1.5 JSON SBOM.
+
+CHART_PATH may be a chart directory, a packaged chart (.tgz) or a local OCI
+layout directory.`,
+		Args: cobra.ExactArgs(1),
+		RunE: runImages,
+	}
+
+	// Add flags specific to images command
+	f := imagesCmd.Flags()
+	f.StringVar(&imagesFormat, "format", images.FormatText, "Output format: "+strings.Join(images.KnownFormats, "|"))
+	f.StringSliceVarP(&imagesValueFiles, "values", "f", nil, "Values files used to render the chart (can be repeated)")
+	f.StringVar(&imagesReleaseName, "release-name", render.DefaultReleaseName, "Release name used to render the chart")
+	f.StringVar(&imagesNamespace, "namespace", render.DefaultNamespace, "Namespace used to render the chart")
+
+	return imagesCmd
+}
+
+// runImages implements the images command logic
+func runImages(cmd *cobra.Command, args []string) error {
+	ws, err := openWorkspace(args[0])
+	if err != nil {
+		return err
+	}
+	defer ws.Close()
+
+	return images.Run(images.Options{
+		ChartPath:   ws.ChartPath,
+		ValueFiles:  imagesValueFiles,
+		ReleaseName: imagesReleaseName,
+		Namespace:   imagesNamespace,
+		Format:      imagesFormat,
+		Verbose:     IsVerbose(),
+	})
+}
@@ -0,0 +1,70 @@
+package commands
+
+import (
+	"github.com/harness/helm-optimize/pkg/index"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// Index command flags
+	indexOutput         string
+	indexKeepLast       int
+	indexKeep           []string
+	indexDropDeprecated bool
+	indexRemoveOrphans  bool
+	indexDryRun         bool
+	indexShowDeleted    bool
+)
+
+// NewIndexCmd creates the index subcommand
+func NewIndexCmd() *cobra.Command {
+	var indexCmd = &cobra.Command{
+		Use:   "index REPO_DIR|INDEX_FILE",
+		Short: "Prune chart versions from a repository index.yaml",
+		Long: `Optimize the index.yaml of a static chart repository.
+
+Versions of every chart are pruned by policy: --keep-last keeps the newest N
+versions, --keep keeps versions matching semver ranges (a version is kept when
+either rule keeps it), and --drop-deprecated removes deprecated versions.
+Versions whose digest repeats a newer kept version are always removed.
+Without --keep-last or --keep only deprecated and duplicate versions are
+removed.
+
+With --remove-orphans, the archives in the repository directory that only
+removed versions referred to are deleted as well.`,
+		Args: cobra.ExactArgs(1),
+		RunE: runIndex,
+	}
+
+	// Add flags specific to index command
+	f := indexCmd.Flags()
+	f.StringVarP(&indexOutput, "output", "o", "", "Write the optimized index to this file (default: overwrite the index)")
+	f.IntVar(&indexKeepLast, "keep-last", 0, "Keep the newest N versions of every chart (0 keeps all)")
+	f.StringArrayVar(&indexKeep, "keep", nil, "Keep versions matching this semver range, e.g. '>=2.0.0 <3.0.0' (can be repeated)")
+	f.BoolVar(&indexDropDeprecated, "drop-deprecated", false, "Remove versions marked as deprecated")
+	f.BoolVar(&indexRemoveOrphans, "remove-orphans", false, "Delete archives in the repository directory that only removed versions refer to (not with --output to another file)")
+	f.BoolVar(&indexDryRun, "dry-run", false, "Show the versions that would be removed without changing anything")
+	f.BoolVar(&indexShowDeleted, "show-deleted", false, "Show archives that are deleted")
+
+	return indexCmd
+}
+
+// runIndex implements the index command logic
+func runIndex(cmd *cobra.Command, args []string) error {
+	// Archives only removed versions refer to are still listed in the source index
+	if indexRemoveOrphans && indexOutput != "" && !index.SameIndex(args[0], indexOutput) {
+		return usageErrorf("--remove-orphans cannot be combined with --output %s: the archives are still referenced by the source index", indexOutput)
+	}
+
+	return index.Run(index.Options{
+		Path:           args[0],
+		Output:         indexOutput,
+		KeepLast:       indexKeepLast,
+		Keep:           indexKeep,
+		DropDeprecated: indexDropDeprecated,
+		RemoveOrphans:  indexRemoveOrphans,
+		DryRun:         indexDryRun,
+		ShowDeleted:    indexShowDeleted,
+		Verbose:        IsVerbose(),
+	})
+}
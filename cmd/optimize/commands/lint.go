@@ -0,0 +1,52 @@
+package commands
+
+import (
+	"os"
+
+	"github.com/harness/helm-optimize/pkg/lint"
+	"github.com/spf13/pflag"
+)
+
+var (
+	// Lint flags shared by the commands that change charts
+	lintChart  bool
+	lintStrict bool
+	lintValues []string
+)
+
+// addLintFlags adds the flags linting a chart before and after optimization
+func addLintFlags(f *pflag.FlagSet) {
+	f.BoolVar(&lintChart, "lint", false, "Run 'helm lint' before and after the optimization and fail if it introduces lint errors")
+	f.BoolVar(&lintStrict, "lint-strict", false, "Also fail on introduced lint warnings (implies --lint)")
+	f.StringSliceVar(&lintValues, "lint-values", nil, "Values files used when linting (can be repeated)")
+}
+
+// lintOptions returns the lint options selected by the flags, or nil when linting is disabled
+func lintOptions() *lint.Options {
+	if !lintChart && !lintStrict {
+		return nil
+	}
+	return &lint.Options{Strict: lintStrict, ValueFiles: lintValues}
+}
+
+// withLint runs an optimization between two lint runs of the chart when
+// --lint is given. Dry runs leave the chart unchanged and are not linted.
+func withLint(chartPath string, dryRun bool, optimize func() error) error {
+	opts := lintOptions()
+	if opts == nil || dryRun {
+		return optimize()
+	}
+
+	guard, err := lint.Begin(chartPath, *opts)
+	if err != nil {
+		return err
+	}
+	if err := optimize(); err != nil {
+		return err
+	}
+	delta, err := guard.End()
+	if delta != nil {
+		delta.Print(os.Stdout)
+	}
+	return err
+}
@@ -0,0 +1,111 @@
+package commands
+
+import (
+	"strings"
+
+	"github.com/harness/helm-optimize/pkg/prune"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// Prune command flags
+	pruneValueSets   []string
+	pruneDryRun      bool
+	pruneShowDeleted bool
+	pruneKeep        []string
+	pruneExclude     []string
+	pruneOutputDir   string
+)
+
+// NewPruneCmd creates the prune-disabled subcommand
+func NewPruneCmd() *cobra.Command {
+	var pruneCmd = &cobra.Command{
+		Use:   "prune-disabled CHART_PATH...",
+		Short: "Remove subcharts disabled by conditions and tags",
+		Long: `Remove subcharts that are disabled by their 'condition' or 'tags' under
+every supplied values set.
+
+Each --values flag is one values set; a set may merge several files given as a
+comma separated list (-f base.yaml,prod.yaml). The dependency conditions and
+tags are evaluated with Helm's own semantics, and a subchart is only removed
+when it is disabled in all sets. The dependency entry is removed from the
+parent Chart.yaml and Chart.lock as well, and the report lists which values
+keys disabled each subchart.
+
+CHART_PATH may be a chart directory, a packaged chart (.tgz) or a local OCI
+layout directory.` + batchUsage,
+		Args:    cobra.MinimumNArgs(1),
+		PreRunE: loadSigner,
+		RunE:    runPrune,
+	}
+
+	// Add flags specific to prune-disabled command
+	f := pruneCmd.Flags()
+	addBatchFlags(f)
+	addSignFlags(f)
+	addCheckFlag(f)
+	addLintFlags(f)
+	f.StringArrayVarP(&pruneValueSets, "values", "f", nil, "Values set to evaluate: one or more comma separated values files (can be repeated)")
+	f.StringVarP(&pruneOutputDir, "output", "o", "", "Output directory for the new archive when CHART_PATH is an archive (default: directory containing CHART_PATH)")
+	f.BoolVar(&pruneDryRun, "dry-run", false, "Show disabled subcharts without removing them")
+	f.BoolVar(&pruneShowDeleted, "show-deleted", false, "Show paths that would be deleted")
+	f.StringSliceVar(&pruneKeep, "keep", nil, "Never delete subcharts matching these path globs or chart names (added to the configuration file)")
+	f.StringSliceVar(&pruneExclude, "exclude", nil, "Skip subcharts matching these path globs or chart names (added to the configuration file)")
+
+	return pruneCmd
+}
+
+// runPrune implements the prune-disabled command logic
+func runPrune(cmd *cobra.Command, args []string) error {
+	return runBatch(args, func(source string) error {
+		return runPruneChart(cmd, source)
+	})
+}
+
+// runPruneChart runs the prune-disabled command against a single chart source
+func runPruneChart(cmd *cobra.Command, source string) error {
+	ws, err := openWorkspace(source)
+	if err != nil {
+		return err
+	}
+	defer ws.Close()
+
+	cfg, err := loadConfig(ws.ChartPath)
+	if err != nil {
+		return err
+	}
+
+	opts := prune.Options{
+		ChartPath:   ws.ChartPath,
+		ValueSets:   parseValueSets(override(cmd, "values", pruneValueSets, cfg.PruneDisabled.Values)),
+		DryRun:      pruneDryRun || checkMode,
+		ShowDeleted: pruneShowDeleted,
+		Check:       checkMode,
+		Verbose:     IsVerbose(),
+		Exclude:     append(cfg.Charts.Exclude, pruneExclude...),
+		NeverDelete: append(cfg.NeverDelete, pruneKeep...),
+	}
+
+	if err := withLint(ws.ChartPath, opts.DryRun, func() error { return prune.Run(opts) }); err != nil {
+		return err
+	}
+
+	return repackage(ws, pruneOutputDir, opts.DryRun)
+}
+
+// parseValueSets splits comma separated values files into values sets
+func parseValueSets(sets []string) [][]string {
+	var out [][]string
+	for _, set := range sets {
+		var files []string
+		for _, file := range strings.Split(set, ",") {
+			if file = strings.TrimSpace(file); file != "" {
+				files = append(files, file)
+			}
+		}
+		if len(files) > 0 {
+			out = append(out, files)
+		}
+	}
+	return out
+}
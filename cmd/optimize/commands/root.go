@@ -1,12 +1,23 @@
 package commands
 
 import (
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/harness/helm-optimize/pkg/logging"
 	"github.com/spf13/cobra"
 )
 
 var (
 	// Global flags
-	verbose bool
+	verbose    bool
+	configFile string
+	logLevel   string
+	logFormat  string
+
+	// logger is configured from the logging flags before any command runs
+	logger = slog.Default()
 )
 
 // NewRootCmd creates the root command
@@ -18,18 +29,64 @@ func NewRootCmd() *cobra.Command {
 
 This plugin provides multiple optimization features for Helm charts,
 helping to improve performance, reduce size, and enhance usability.`,
+		PersistentPreRunE: configureLogging,
+		// main prints the error once and maps it to an exit code
+		SilenceErrors: true,
 	}
 
 	// Add global flags
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Enable verbose output")
+	rootCmd.PersistentFlags().StringVar(&configFile, "config", "", "Path to the configuration file (default: CHART_PATH/.helm-optimize.yaml)")
+	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "info", "Minimum level of the diagnostics written to stderr: "+strings.Join(logging.KnownLevels, "|")+" (--verbose implies debug)")
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", logging.FormatText, "Format of the diagnostics written to stderr: "+strings.Join(logging.KnownFormats, "|"))
 
 	// Add subcommands
 	rootCmd.AddCommand(NewDedupCmd())
 	rootCmd.AddCommand(NewCleanupCmd())
+	rootCmd.AddCommand(NewAnalyzeCmd())
+	rootCmd.AddCommand(NewDiffCmd())
+	rootCmd.AddCommand(NewValidateCmd())
+	rootCmd.AddCommand(NewSchemaCmd())
+	rootCmd.AddCommand(NewAuditCmd())
+	rootCmd.AddCommand(NewPruneCmd())
+	rootCmd.AddCommand(NewImagesCmd())
+	rootCmd.AddCommand(NewExtractCRDsCmd())
+	rootCmd.AddCommand(NewHelpersCmd())
+	rootCmd.AddCommand(NewIndexCmd())
+	rootCmd.AddCommand(NewVerifyCmd())
+	rootCmd.AddCommand(NewRunCmd())
+	rootCmd.AddCommand(NewCacheCmd())
+	rootCmd.AddCommand(NewConfigCmd())
 
 	return rootCmd
 }
 
+// configureLogging creates the logger writing diagnostics to stderr, keeping
+// stdout for command output
+func configureLogging(cmd *cobra.Command, args []string) error {
+	level := logLevel
+	if verbose && !cmd.Flags().Changed("log-level") {
+		level = "debug"
+	}
+
+	l, err := logging.New(os.Stderr, level, logFormat)
+	if err != nil {
+		return err
+	}
+	logger = l
+	slog.SetDefault(l)
+
+	// Arguments and flags were accepted: later errors are not usage errors
+	cmd.SilenceUsage = true
+	commandStarted = true
+	return nil
+}
+
+// Logger returns the logger configured from the global flags
+func Logger() *slog.Logger {
+	return logger
+}
+
 // IsVerbose returns the global verbose flag value
 func IsVerbose() bool {
 	return verbose
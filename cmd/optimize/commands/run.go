@@ -0,0 +1,210 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/harness/helm-optimize/pkg/cleanup"
+	"github.com/harness/helm-optimize/pkg/common"
+	"github.com/harness/helm-optimize/pkg/config"
+	"github.com/harness/helm-optimize/pkg/dedup"
+	"github.com/harness/helm-optimize/pkg/lint"
+	"github.com/harness/helm-optimize/pkg/prune"
+	"github.com/harness/helm-optimize/pkg/release"
+	"github.com/harness/helm-optimize/pkg/strip"
+	"github.com/spf13/cobra"
+	"helm.sh/helm/v3/pkg/chart/loader"
+)
+
+var (
+	// Run command flags
+	runDryRun      bool
+	runShowDeleted bool
+)
+
+// NewRunCmd creates the run subcommand
+func NewRunCmd() *cobra.Command {
+	var runCmd = &cobra.Command{
+		Use:   "run [CHART_PATH]",
+		Short: "Run the optimizations declared in the configuration file",
+		Long: `Run every optimization listed under 'optimizations' in the configuration file.
+
+The optimizations are executed in the order they are listed, using the settings
+of the configuration file. Command line flags override the file values.`,
+		Args: cobra.ExactArgs(1),
+		RunE: runRun,
+	}
+
+	// Add flags specific to run command
+	f := runCmd.Flags()
+	f.BoolVar(&runDryRun, "dry-run", false, "Simulate the optimizations without making changes")
+	f.BoolVar(&runShowDeleted, "show-deleted", false, "Show paths that would be deleted")
+	addCheckFlag(f)
+	addLintFlags(f)
+
+	return runCmd
+}
+
+// runRun implements the run command logic
+func runRun(cmd *cobra.Command, args []string) error {
+	chartPath := args[0]
+
+	cfg, err := loadConfig(chartPath)
+	if err != nil {
+		return err
+	}
+	if len(cfg.Optimizations) == 0 {
+		return fmt.Errorf("no optimizations configured for chart '%s'", chartPath)
+	}
+
+	dryRun := override(cmd, "dry-run", runDryRun, false) || checkMode
+
+	// Track the release record size Helm would store across the optimizations
+	size, sized := measureRelease(chartPath)
+	if sized {
+		fmt.Printf("Release size: %d bytes (storage limit %d bytes)\n", size, release.StorageLimit)
+	}
+
+	// Lint before the first optimization, so that only new findings are reported
+	var guard *lint.Guard
+	if opts := lintOptions(); opts != nil && !dryRun {
+		if guard, err = lint.Begin(chartPath, *opts); err != nil {
+			return err
+		}
+	}
+
+	// In check mode every optimization is checked before failing
+	var unoptimized []string
+	for _, name := range cfg.Optimizations {
+		fmt.Printf("Running optimization '%s'...\n", name)
+		if err := runOptimization(cmd, name, chartPath, cfg); err != nil {
+			if checkMode && errors.Is(err, common.ErrNotOptimized) {
+				unoptimized = append(unoptimized, name)
+				continue
+			}
+			return fmt.Errorf("optimization '%s' failed: %w", name, err)
+		}
+		if dryRun {
+			continue
+		}
+		if after, ok := measureRelease(chartPath); ok {
+			if sized {
+				fmt.Printf("Release size after '%s': %d bytes (was %d bytes)\n", name, after, size)
+			} else {
+				fmt.Printf("Release size after '%s': %d bytes\n", name, after)
+			}
+			size, sized = after, true
+		}
+	}
+	if sized && size > release.StorageLimit {
+		fmt.Printf("WARNING: the release record exceeds the %d bytes storage limit and cannot be installed\n", release.StorageLimit)
+	}
+
+	if len(unoptimized) > 0 {
+		return fmt.Errorf("%w: %s would change the chart", common.ErrNotOptimized, strings.Join(unoptimized, ", "))
+	}
+
+	if guard != nil {
+		delta, err := guard.End()
+		if delta != nil {
+			delta.Print(os.Stdout)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if cfg.Verify.Load && !dryRun {
+		if err := verifyLoad(chartPath); err != nil {
+			return err
+		}
+		fmt.Println("Verification passed: optimized chart loads successfully.")
+	}
+
+	return nil
+}
+
+// verifyLoad checks that Helm can still load the optimized chart
+func verifyLoad(chartPath string) error {
+	if _, err := loader.Load(chartPath); err != nil {
+		return fmt.Errorf("%w: optimized chart cannot be loaded: %w", common.ErrVerificationFailed, err)
+	}
+	return nil
+}
+
+// measureRelease estimates the release record size of the chart with its
+// default values. Charts that cannot be rendered yet, e.g. before 'cleanup'
+// fetched their dependencies, are reported in verbose mode only.
+func measureRelease(chartPath string) (int, bool) {
+	size, err := release.Measure(chartPath, release.Options{})
+	if err != nil {
+		if IsVerbose() {
+			fmt.Printf("Release size unavailable: %v\n", err)
+		}
+		return 0, false
+	}
+	return size, true
+}
+
+// runOptimization executes a single configured optimization
+func runOptimization(cmd *cobra.Command, name, chartPath string, cfg *config.Config) error {
+	switch name {
+	case config.OptimizationCleanup:
+		return cleanup.Run(cleanup.Options{
+			ChartPath:   chartPath,
+			DryRun:      override(cmd, "dry-run", runDryRun, cfg.Cleanup.DryRun),
+			ShowDeleted: override(cmd, "show-deleted", runShowDeleted, cfg.Cleanup.ShowDeleted),
+			Check:       checkMode,
+			Logger:      Logger(),
+			Include:     cfg.Charts.Include,
+			Exclude:     cfg.Charts.Exclude,
+			NeverDelete: cfg.NeverDelete,
+		})
+	case config.OptimizationDedup:
+		return dedup.Run(dedup.Options{
+			ChartPath:    chartPath,
+			OutputDir:    cfg.Dedup.Output,
+			Package:      cfg.Dedup.Package,
+			DryRun:       override(cmd, "dry-run", runDryRun, cfg.Dedup.DryRun),
+			ShowDeleted:  override(cmd, "show-deleted", runShowDeleted, cfg.Dedup.ShowDeleted),
+			Check:        checkMode,
+			Logger:       Logger(),
+			Include:      cfg.Charts.Include,
+			Exclude:      cfg.Charts.Exclude,
+			NeverDelete:  cfg.NeverDelete,
+			KeepStrategy: cfg.Dedup.KeepStrategy,
+			Prefer:       cfg.Dedup.Prefer,
+			ValuesCheck:  cfg.Dedup.ValuesCheck,
+			ValueFiles:   cfg.Dedup.Values,
+			Push:         cfg.Dedup.Push,
+			PlainHTTP:    cfg.Dedup.PlainHTTP,
+		})
+	case config.OptimizationPruneDisabled:
+		return prune.Run(prune.Options{
+			ChartPath:   chartPath,
+			ValueSets:   parseValueSets(cfg.PruneDisabled.Values),
+			DryRun:      override(cmd, "dry-run", runDryRun, false),
+			ShowDeleted: override(cmd, "show-deleted", runShowDeleted, false),
+			Check:       checkMode,
+			Verbose:     IsVerbose(),
+			Exclude:     cfg.Charts.Exclude,
+			NeverDelete: cfg.NeverDelete,
+		})
+	case config.OptimizationStrip:
+		return strip.Run(strip.Options{
+			ChartPath:   chartPath,
+			Patterns:    cfg.StripPatterns(),
+			DryRun:      override(cmd, "dry-run", runDryRun, false),
+			ShowDeleted: override(cmd, "show-deleted", runShowDeleted, false),
+			Check:       checkMode,
+			Verbose:     IsVerbose(),
+			Include:     cfg.Charts.Include,
+			Exclude:     cfg.Charts.Exclude,
+			NeverDelete: cfg.NeverDelete,
+		})
+	default:
+		return fmt.Errorf("unknown optimization '%s'", name)
+	}
+}
@@ -0,0 +1,59 @@
+package commands
+
+import (
+	"github.com/harness/helm-optimize/pkg/schema"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// Schema command flags
+	schemaOutput     string
+	schemaValueFiles []string
+)
+
+// NewSchemaCmd creates the schema subcommand
+func NewSchemaCmd() *cobra.Command {
+	var schemaCmd = &cobra.Command{
+		Use:   "schema CHART_PATH",
+		Short: "Compose and validate an umbrella values schema",
+		Long: `Compose a single JSON Schema for the values of an umbrella chart from the
+values.schema.json files of all its subcharts.
+
+Each subchart schema is nested under the values key of the subchart, its alias
+or name. Identical schemas, e.g. the same subchart used in several places, are
+stored once under $defs and referenced from every scope. The constraints that
+subcharts place on global values also apply to the umbrella's global values.
+
+The schema is written to --output, or to stdout. With --values the given files,
+on top of the chart defaults, are validated against the composed schema and
+the command exits with code 8 if they do not match.
+
+CHART_PATH may be a chart directory, a packaged chart (.tgz) or a local OCI
+layout directory.`,
+		Args: cobra.ExactArgs(1),
+		RunE: runSchema,
+	}
+
+	// Add flags specific to schema command
+	f := schemaCmd.Flags()
+	f.StringVarP(&schemaOutput, "output", "o", "", "Write the composed schema to this file (default: stdout unless --values is given)")
+	f.StringSliceVarP(&schemaValueFiles, "values", "f", nil, "Values files validated against the composed schema (can be repeated)")
+
+	return schemaCmd
+}
+
+// runSchema implements the schema command logic
+func runSchema(cmd *cobra.Command, args []string) error {
+	ws, err := openWorkspace(args[0])
+	if err != nil {
+		return err
+	}
+	defer ws.Close()
+
+	return schema.Run(schema.Options{
+		ChartPath:  ws.ChartPath,
+		Output:     schemaOutput,
+		ValueFiles: schemaValueFiles,
+		Verbose:    IsVerbose(),
+	})
+}
@@ -0,0 +1,18 @@
+package commands
+
+import (
+	"strings"
+
+	"github.com/harness/helm-optimize/pkg/common"
+	"github.com/spf13/pflag"
+)
+
+var (
+	// Symlinks flag shared by the commands that delete subcharts
+	symlinks string
+)
+
+// addSymlinksFlag adds the flag selecting how symlinked subcharts are handled
+func addSymlinksFlag(f *pflag.FlagSet) {
+	f.StringVar(&symlinks, "symlinks", common.SymlinksFollow, "How to handle symlinked subcharts: "+strings.Join(common.KnownSymlinkPolicies, "|")+"; nothing outside the chart root is ever deleted through a link")
+}
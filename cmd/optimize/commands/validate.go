@@ -0,0 +1,67 @@
+package commands
+
+import (
+	"strings"
+
+	"github.com/harness/helm-optimize/pkg/validate"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// Validate command flags
+	validateValueFiles  []string
+	validateKubeVersion string
+	validateStrict      bool
+	validateFormat      string
+)
+
+// NewValidateCmd creates the validate subcommand
+func NewValidateCmd() *cobra.Command {
+	var validateCmd = &cobra.Command{
+		Use:   "validate CHART_PATH",
+		Short: "Validate the rendered manifests against Kubernetes schemas",
+		Long: `Render the chart tree with the Helm engine and validate every object against
+the OpenAPI schemas of a Kubernetes version bundled with the plugin. No cluster
+is needed.
+
+The CRDs shipped in crds/ directories or rendered by templates are validated
+like any other object, and the custom resources of the chart are validated
+against their schemas. Objects using an API the selected version does not
+serve fail; custom resources without schema are skipped unless --strict is
+given, which also rejects unknown fields.
+
+Failures are reported by subchart and template. The command exits with code 8
+if any object is invalid.
+
+CHART_PATH may be a chart directory, a packaged chart (.tgz) or a local OCI
+layout directory.`,
+		Args: cobra.ExactArgs(1),
+		RunE: runValidate,
+	}
+
+	// Add flags specific to validate command
+	f := validateCmd.Flags()
+	f.StringSliceVarP(&validateValueFiles, "values", "f", nil, "Values files used to render the chart (can be repeated)")
+	f.StringVar(&validateKubeVersion, "kube-version", validate.DefaultVersion(), "Kubernetes version to validate against: "+strings.Join(validate.KnownVersions(), "|"))
+	f.BoolVar(&validateStrict, "strict", false, "Reject unknown fields and objects without a schema")
+	f.StringVar(&validateFormat, "format", validate.FormatText, "Output format: "+strings.Join(validate.KnownFormats, "|"))
+
+	return validateCmd
+}
+
+// runValidate implements the validate command logic
+func runValidate(cmd *cobra.Command, args []string) error {
+	ws, err := openWorkspace(args[0])
+	if err != nil {
+		return err
+	}
+	defer ws.Close()
+
+	return validate.Run(validate.Options{
+		ChartPath:   ws.ChartPath,
+		ValueFiles:  validateValueFiles,
+		KubeVersion: validateKubeVersion,
+		Strict:      validateStrict,
+		Format:      validateFormat,
+	})
+}
@@ -0,0 +1,50 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/harness/helm-optimize/pkg/workspace"
+)
+
+// openWorkspace prepares the chart source given on the command line.
+// Archives and OCI layouts are extracted to a temporary directory.
+func openWorkspace(source string) (*workspace.Workspace, error) {
+	ws, err := workspace.Open(source)
+	if err != nil {
+		return nil, err
+	}
+	if ws.IsTemporary() {
+		Logger().Debug("extracted chart source", "kind", ws.Kind, "source", source, "dir", ws.ChartPath)
+	}
+	return ws, nil
+}
+
+// repackage writes the optimized chart of an archive or OCI layout source to
+// a new archive, signed when --sign is given. Directory sources are optimized
+// in place and left untouched; an archive is never overwritten.
+func repackage(ws *workspace.Workspace, outputDir string, dryRun bool) error {
+	if !ws.IsTemporary() || dryRun {
+		return nil
+	}
+	if outputDir == "" {
+		outputDir = ws.DefaultOutputDir()
+	}
+	if err := ws.CheckOutput(outputDir); err != nil {
+		return err
+	}
+
+	archive, err := workspace.Package(ws.ChartPath, outputDir)
+	if err != nil {
+		return fmt.Errorf("failed to package optimized chart: %w", err)
+	}
+	fmt.Printf("Optimized chart written to: %s\n", archive)
+
+	if signer != nil {
+		prov, err := signer.Sign(archive)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Provenance written to: %s\n", prov)
+	}
+	return nil
+}
@@ -14,6 +14,6 @@ func main() {
 	// Execute the root command
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
-		os.Exit(1)
+		os.Exit(commands.ExitCode(err))
 	}
 }
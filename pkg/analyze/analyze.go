@@ -0,0 +1,224 @@
+package analyze
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/harness/helm-optimize/pkg/common"
+	"github.com/harness/helm-optimize/pkg/dedup"
+	"github.com/harness/helm-optimize/pkg/release"
+)
+
+// Output formats supported by the analyze command
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Options defines the parameters for analyzing a chart
+type Options struct {
+	// ChartPath is the unpacked chart directory to analyze
+	ChartPath string
+	// Source is the chart path given by the user (directory, archive or OCI layout)
+	Source       string
+	Format       string
+	KeepStrategy string
+	Prefer       []string
+	ValuesCheck  string
+	ValueFiles   []string
+	Include      []string
+	Exclude      []string
+	NeverDelete  []string
+}
+
+// Report describes the optimization potential of a chart
+type Report struct {
+	Source string `json:"source"`
+	// Charts is the number of charts in the tree, including the root chart
+	Charts int `json:"charts"`
+	// TotalBytes is the size of all files in the chart tree
+	TotalBytes int64 `json:"totalBytes"`
+	// ReclaimableBytes is the size of the duplicate copies dedup would remove
+	ReclaimableBytes int64        `json:"reclaimableBytes"`
+	Dedup            dedup.Report `json:"dedup"`
+	// Release is the encoded size of the release record Helm would store
+	Release ReleaseSize `json:"release"`
+}
+
+// ReleaseSize compares the release record size before and after deduplication
+// with the Helm storage limit
+type ReleaseSize struct {
+	Before int `json:"before"`
+	After  int `json:"after"`
+	Limit  int `json:"limit"`
+	// Fits reports whether the release can be stored after deduplication
+	Fits bool `json:"fits"`
+	// Error explains why the size could not be estimated
+	Error string `json:"error,omitempty"`
+}
+
+// Run analyzes the chart and writes the report to stdout
+func Run(opts Options) error {
+	report, err := Analyze(opts)
+	if err != nil {
+		return err
+	}
+	return report.Write(os.Stdout, opts.Format)
+}
+
+// Analyze builds the report for a chart without changing it
+func Analyze(opts Options) (*Report, error) {
+	if opts.Source == "" {
+		opts.Source = opts.ChartPath
+	}
+
+	deduplicator := dedup.NewDeduplicator(dedup.Options{
+		ChartPath:    opts.ChartPath,
+		DryRun:       true,
+		KeepStrategy: opts.KeepStrategy,
+		Prefer:       opts.Prefer,
+		ValuesCheck:  opts.ValuesCheck,
+		ValueFiles:   opts.ValueFiles,
+		Include:      opts.Include,
+		Exclude:      opts.Exclude,
+		NeverDelete:  opts.NeverDelete,
+	})
+	dedupReport, err := deduplicator.Analyze(opts.ChartPath)
+	if err != nil {
+		return nil, fmt.Errorf("dedup analysis failed: %w", err)
+	}
+
+	report := &Report{Source: opts.Source, Dedup: dedupReport.Relative(opts.ChartPath)}
+	report.Dedup.ChartPath = opts.Source
+
+	if report.TotalBytes, err = common.DirSize(opts.ChartPath); err != nil {
+		return nil, fmt.Errorf("failed to measure chart: %w", err)
+	}
+	if report.Charts, err = countCharts(opts.ChartPath); err != nil {
+		return nil, err
+	}
+	for _, path := range dedupReport.Deleted {
+		size, err := common.DirSize(path)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to measure %s: %w", path, err)
+		}
+		report.ReclaimableBytes += size
+	}
+
+	report.Release = estimateRelease(opts, dedupReport.Deleted)
+
+	return report, nil
+}
+
+// estimateRelease measures the release record of the chart, and of a copy
+// with the duplicate dependencies removed. Charts that cannot be rendered get
+// an error instead of sizes, without failing the analysis.
+func estimateRelease(opts Options, deleted []string) ReleaseSize {
+	size := ReleaseSize{Limit: release.StorageLimit}
+	releaseOpts := release.Options{ValueFiles: opts.ValueFiles}
+
+	before, err := release.Measure(opts.ChartPath, releaseOpts)
+	if err != nil {
+		size.Error = err.Error()
+		return size
+	}
+	size.Before, size.After = before, before
+
+	if len(deleted) > 0 {
+		after, err := measureDeduplicated(opts.ChartPath, deleted, releaseOpts)
+		if err != nil {
+			size.Error = err.Error()
+			return size
+		}
+		size.After = after
+	}
+
+	size.Fits = size.After <= size.Limit
+	return size
+}
+
+// measureDeduplicated measures the release record of a temporary copy of the
+// chart without the deleted dependency copies
+func measureDeduplicated(chartPath string, deleted []string, opts release.Options) (int, error) {
+	tempDir, err := os.MkdirTemp("", "helm-optimize-")
+	if err != nil {
+		return 0, fmt.Errorf("failed to create workspace: %w", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	copyPath := filepath.Join(tempDir, filepath.Base(filepath.Clean(chartPath)))
+	if err := common.CopyDir(chartPath, copyPath); err != nil {
+		return 0, fmt.Errorf("failed to copy chart: %w", err)
+	}
+	for _, path := range deleted {
+		rel, err := filepath.Rel(chartPath, path)
+		if err != nil {
+			return 0, err
+		}
+		if err := os.RemoveAll(filepath.Join(copyPath, rel)); err != nil {
+			return 0, err
+		}
+	}
+
+	return release.Measure(copyPath, opts)
+}
+
+// Write renders the report in the given format
+func (r *Report) Write(w io.Writer, format string) error {
+	switch format {
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	case FormatText, "":
+		fmt.Fprintf(w, "Chart:             %s\n", r.Source)
+		fmt.Fprintf(w, "Charts in tree:    %d\n", r.Charts)
+		fmt.Fprintf(w, "Total size:        %d bytes\n", r.TotalBytes)
+		fmt.Fprintf(w, "Duplicate groups:  %d\n", len(r.Dedup.Groups))
+		fmt.Fprintf(w, "Reclaimable:       %d bytes (%d duplicate copies)\n", r.ReclaimableBytes, len(r.Dedup.Deleted))
+		if r.Release.Error != "" {
+			fmt.Fprintf(w, "Release size:      unknown (%s)\n", r.Release.Error)
+		} else {
+			verdict := "fits"
+			if !r.Release.Fits {
+				verdict = "EXCEEDS"
+			}
+			fmt.Fprintf(w, "Release size:      %d bytes, %d bytes after dedup (%s the %d bytes storage limit)\n",
+				r.Release.Before, r.Release.After, verdict, r.Release.Limit)
+		}
+		for _, group := range r.Dedup.Groups {
+			fmt.Fprintf(w, "  %s: keep %s (%s)\n", group.Dependency, group.Kept, group.Reason)
+			for _, path := range group.Removed {
+				fmt.Fprintf(w, "    remove %s\n", path)
+			}
+			for _, p := range group.Protected {
+				fmt.Fprintf(w, "    protected %s (%s)\n", p.Path, p.Reason)
+			}
+			for _, c := range group.ValueConflicts {
+				fmt.Fprintf(w, "    values differ at %s: %s\n", c.Path, strings.Join(c.Keys, ", "))
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown output format '%s' (known: %s, %s)", format, FormatText, FormatJSON)
+	}
+}
+
+// countCharts counts the chart directories in the tree
+func countCharts(root string) (int, error) {
+	count := 0
+	err := filepath.WalkDir(root, func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() && entry.Name() == "Chart.yaml" {
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
@@ -0,0 +1,211 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/harness/helm-optimize/pkg/common"
+	"github.com/harness/helm-optimize/pkg/release"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+)
+
+// Output formats supported by the audit-size command
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Finding kinds
+const (
+	KindLargeFile   = "large-file"
+	KindBinaryFile  = "binary-file"
+	KindReleaseSize = "release-size"
+)
+
+// Severities of findings. Files are warnings; a release that cannot be stored is an error.
+const (
+	SeverityWarning = "warning"
+	SeverityError   = "error"
+)
+
+// DefaultMaxFileSize is the file size above which a file is reported
+const DefaultMaxFileSize = 100 * 1024
+
+// binarySniffLen is how many leading bytes are inspected to detect binary files
+const binarySniffLen = 8000
+
+// Options defines the parameters for the size audit
+type Options struct {
+	ChartPath string
+	// MaxFileSize is the size in bytes above which a file is reported
+	MaxFileSize int64
+	// MaxReleaseSize is the encoded release size in bytes Helm storage accepts
+	MaxReleaseSize int64
+	// ValueFiles are used to render the release for the size estimate
+	ValueFiles []string
+	Verbose    bool
+}
+
+// Finding is a single audit result
+type Finding struct {
+	Kind     string `json:"kind"`
+	Severity string `json:"severity"`
+	// Path is the file path inside the packaged chart, e.g. "umbrella/charts/a/files/blob.bin"
+	Path    string `json:"path,omitempty"`
+	Size    int64  `json:"size"`
+	Message string `json:"message"`
+}
+
+// Report is the result of a size audit
+type Report struct {
+	Chart string `json:"chart"`
+	// ReleaseSize is the estimated encoded size of the release record
+	ReleaseSize    int64     `json:"releaseSize"`
+	MaxReleaseSize int64     `json:"maxReleaseSize"`
+	Findings       []Finding `json:"findings"`
+}
+
+// Audit loads the chart as Helm packages it and reports large files, binary
+// files and a release record exceeding the storage limit
+func Audit(opts Options) (*Report, error) {
+	if opts.MaxFileSize <= 0 {
+		opts.MaxFileSize = DefaultMaxFileSize
+	}
+	if opts.MaxReleaseSize <= 0 {
+		opts.MaxReleaseSize = release.StorageLimit
+	}
+
+	// The loader applies .helmignore, so only packaged files are inspected
+	c, err := loader.Load(opts.ChartPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load chart: %w", err)
+	}
+
+	report := &Report{Chart: c.Name(), MaxReleaseSize: opts.MaxReleaseSize, Findings: []Finding{}}
+	walkFiles(c, func(path string, data []byte, root bool) {
+		size := int64(len(data))
+		if size > opts.MaxFileSize {
+			report.Findings = append(report.Findings, Finding{
+				Kind:     KindLargeFile,
+				Severity: SeverityWarning,
+				Path:     path,
+				Size:     size,
+				Message:  fmt.Sprintf("file is %s, above the %s threshold", common.FormatSize(size), common.FormatSize(opts.MaxFileSize)),
+			})
+		}
+		if isBinary(data) {
+			// Helm stores the files of the root chart, not of its subcharts, in the release record
+			message := "binary file inflates the chart package"
+			if root {
+				message = "binary file is stored in every release revision"
+			}
+			report.Findings = append(report.Findings, Finding{
+				Kind:     KindBinaryFile,
+				Severity: SeverityWarning,
+				Path:     path,
+				Size:     size,
+				Message:  message,
+			})
+		}
+	})
+	sort.SliceStable(report.Findings, func(i, j int) bool {
+		return report.Findings[i].Path < report.Findings[j].Path
+	})
+
+	releaseSize, err := release.EncodedSize(c, release.Options{ValueFiles: opts.ValueFiles})
+	if err != nil {
+		return nil, fmt.Errorf("failed to estimate release size: %w", err)
+	}
+	report.ReleaseSize = int64(releaseSize)
+	if opts.Verbose {
+		fmt.Fprintf(os.Stderr, "Estimated release record size: %d bytes\n", releaseSize)
+	}
+	if report.ReleaseSize > opts.MaxReleaseSize {
+		report.Findings = append(report.Findings, Finding{
+			Kind:     KindReleaseSize,
+			Severity: SeverityError,
+			Size:     report.ReleaseSize,
+			Message: fmt.Sprintf("encoded release is %s, above the %s Helm storage limit",
+				common.FormatSize(report.ReleaseSize), common.FormatSize(opts.MaxReleaseSize)),
+		})
+	}
+
+	return report, nil
+}
+
+// Has reports whether the report holds a finding of the given severity
+func (r *Report) Has(severity string) bool {
+	for _, f := range r.Findings {
+		if f.Severity == severity {
+			return true
+		}
+	}
+	return false
+}
+
+// Write renders the report in the given format
+func (r *Report) Write(w io.Writer, format string) error {
+	switch format {
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	case FormatText, "":
+		fmt.Fprintf(w, "Chart:         %s\n", r.Chart)
+		fmt.Fprintf(w, "Release size:  %s of %s (%d bytes)\n", common.FormatSize(r.ReleaseSize), common.FormatSize(r.MaxReleaseSize), r.ReleaseSize)
+		for _, f := range r.Findings {
+			if f.Path != "" {
+				fmt.Fprintf(w, "%-7s %-12s %s: %s\n", f.Severity, f.Kind, f.Path, f.Message)
+			} else {
+				fmt.Fprintf(w, "%-7s %-12s %s\n", f.Severity, f.Kind, f.Message)
+			}
+		}
+		if len(r.Findings) == 0 {
+			fmt.Fprintln(w, "No findings.")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown output format '%s' (known: %s, %s)", format, FormatText, FormatJSON)
+	}
+}
+
+// walkFiles calls fn for every file of the chart and its subcharts, telling
+// whether the file belongs to the root chart. The raw files of a chart include
+// its charts/ directory, which is walked through the loaded subcharts instead.
+func walkFiles(c *chart.Chart, fn func(path string, data []byte, root bool)) {
+	prefix := c.ChartFullPath()
+	for _, f := range c.Raw {
+		if strings.HasPrefix(f.Name, "charts/") {
+			continue
+		}
+		fn(prefix+"/"+f.Name, f.Data, c.IsRoot())
+	}
+	for _, dep := range c.Dependencies() {
+		walkFiles(dep, fn)
+	}
+}
+
+// isBinary reports whether the data looks binary: it holds a NUL byte or is
+// not valid UTF-8 in its first bytes
+func isBinary(data []byte) bool {
+	sniff := data
+	if len(sniff) > binarySniffLen {
+		sniff = sniff[:binarySniffLen]
+		// Do not cut a multi-byte character in half
+		for i := 0; i < utf8.UTFMax && !utf8.Valid(sniff); i++ {
+			sniff = sniff[:len(sniff)-1]
+		}
+	}
+	for _, b := range sniff {
+		if b == 0 {
+			return true
+		}
+	}
+	return !utf8.Valid(sniff)
+}
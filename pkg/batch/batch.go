@@ -0,0 +1,129 @@
+package batch
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/harness/helm-optimize/pkg/common"
+)
+
+// Result holds the outcome of running a command against one chart
+type Result struct {
+	Chart    string
+	Err      error
+	Duration time.Duration
+}
+
+// Run executes fn for every chart using at most parallel concurrent workers.
+// Results are returned in the order of the charts.
+func Run(charts []string, parallel int, fn func(chart string) error) []Result {
+	if parallel < 1 {
+		parallel = 1
+	}
+
+	results := make([]Result, len(charts))
+	sem := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+
+	for i, chart := range charts {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, chart string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			start := time.Now()
+			err := fn(chart)
+			results[i] = Result{Chart: chart, Err: err, Duration: time.Since(start)}
+		}(i, chart)
+	}
+
+	wg.Wait()
+	return results
+}
+
+// ReportPath returns the file in dir receiving the report of one chart of a
+// multi-chart run. The name is derived from the chart source, with path
+// separators replaced by underscores, so every chart gets its own file.
+func ReportPath(dir, chart string) string {
+	name := filepath.ToSlash(filepath.Clean(chart))
+	name = strings.TrimSuffix(name, ".tgz")
+	name = strings.Trim(name, "./")
+	if name == "" {
+		if abs, err := filepath.Abs(chart); err == nil {
+			name = filepath.Base(abs)
+		}
+	}
+	return filepath.Join(dir, strings.ReplaceAll(name, "/", "_")+".json")
+}
+
+// Failed returns the results that ended with an error
+func Failed(results []Result) []Result {
+	var failed []Result
+	for _, r := range results {
+		if r.Err != nil {
+			failed = append(failed, r)
+		}
+	}
+	return failed
+}
+
+// PrintSummary writes the per-chart status table
+func PrintSummary(w io.Writer, results []Result) {
+	fmt.Fprintf(w, "\nSummary (%d charts):\n", len(results))
+	for _, r := range results {
+		status := "ok"
+		if r.Err != nil {
+			status = "FAILED"
+		}
+		fmt.Fprintf(w, "  %-6s %s (%s)\n", status, r.Chart, r.Duration.Round(time.Millisecond))
+		if r.Err != nil {
+			fmt.Fprintf(w, "         %v\n", r.Err)
+		}
+	}
+}
+
+// failure is the error of a multi-chart run. It wraps the error of every
+// failed chart, and ErrPartialFailure when some charts succeeded.
+type failure struct {
+	msg  string
+	errs []error
+}
+
+// Error returns the summary message
+func (f *failure) Error() string {
+	return f.msg
+}
+
+// Unwrap returns the wrapped errors
+func (f *failure) Unwrap() []error {
+	return f.errs
+}
+
+// Err returns an error describing the failed charts, or nil when all succeeded
+func Err(results []Result) error {
+	failed := Failed(results)
+	if len(failed) == 0 {
+		return nil
+	}
+	f := &failure{msg: fmt.Sprintf("%d of %d charts failed", len(failed), len(results))}
+	// Charts that are not optimized are check findings, not failures
+	findingsOnly := true
+	for _, r := range failed {
+		if !errors.Is(r.Err, common.ErrNotOptimized) {
+			findingsOnly = false
+		}
+	}
+	if len(failed) < len(results) && !findingsOnly {
+		f.errs = append(f.errs, common.ErrPartialFailure)
+	}
+	for _, r := range failed {
+		f.errs = append(f.errs, r.Err)
+	}
+	return f
+}
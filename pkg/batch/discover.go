@@ -0,0 +1,104 @@
+package batch
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// RecursiveSuffix marks an argument whose chart roots are discovered recursively,
+// as in "./charts/..."
+const RecursiveSuffix = "/..."
+
+// IsPattern reports whether the argument expands to several charts
+func IsPattern(arg string) bool {
+	return strings.HasSuffix(filepath.ToSlash(arg), RecursiveSuffix) || strings.ContainsAny(arg, "*?[")
+}
+
+// Discover expands command line arguments into chart sources.
+// Arguments ending in "/..." are searched recursively for chart roots,
+// arguments containing glob characters are expanded with filepath.Glob,
+// and every other argument is used as given. Duplicates are removed.
+func Discover(args []string) ([]string, error) {
+	var charts []string
+	seen := map[string]bool{}
+	add := func(path string) {
+		clean := filepath.Clean(path)
+		if !seen[clean] {
+			seen[clean] = true
+			charts = append(charts, clean)
+		}
+	}
+
+	for _, arg := range args {
+		switch {
+		case strings.HasSuffix(filepath.ToSlash(arg), RecursiveSuffix):
+			root := strings.TrimSuffix(filepath.ToSlash(arg), RecursiveSuffix)
+			if root == "" || root == "." {
+				root = "."
+			}
+			roots, err := FindChartRoots(filepath.FromSlash(root))
+			if err != nil {
+				return nil, err
+			}
+			if len(roots) == 0 {
+				return nil, fmt.Errorf("no charts found under '%s'", root)
+			}
+			for _, r := range roots {
+				add(r)
+			}
+		case strings.ContainsAny(arg, "*?["):
+			matches, err := filepath.Glob(arg)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern '%s': %w", arg, err)
+			}
+			if len(matches) == 0 {
+				return nil, fmt.Errorf("no charts match '%s'", arg)
+			}
+			for _, m := range matches {
+				add(m)
+			}
+		default:
+			add(arg)
+		}
+	}
+
+	return charts, nil
+}
+
+// FindChartRoots returns every directory below root that holds a Chart.yaml
+// and is not nested in another chart. Hidden directories are skipped.
+func FindChartRoots(root string) ([]string, error) {
+	if _, err := os.Stat(root); err != nil {
+		return nil, err
+	}
+
+	var roots []string
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() {
+			return nil
+		}
+		if path != root && strings.HasPrefix(entry.Name(), ".") {
+			return filepath.SkipDir
+		}
+
+		// Everything below a chart, such as its subcharts, belongs to that chart
+		if _, err := os.Stat(filepath.Join(path, "Chart.yaml")); err == nil {
+			roots = append(roots, path)
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(roots)
+	return roots, nil
+}
@@ -0,0 +1,173 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"helm.sh/helm/v3/pkg/helmpath"
+)
+
+// indexFile holds the digests of the files hashed by previous runs
+const indexFile = "files.json"
+
+// entriesDir holds the cached analysis results, one JSON file per entry
+const entriesDir = "entries"
+
+// DefaultDir returns the cache directory below $HELM_CACHE_HOME
+func DefaultDir() string {
+	return helmpath.CachePath("optimize")
+}
+
+// Cache is a persistent store of analysis results keyed by content digest.
+// A nil *Cache is valid and caches nothing, which is how --no-cache is
+// implemented.
+type Cache struct {
+	dir string
+
+	mu sync.Mutex
+	// files maps absolute file paths to the digest of their content
+	files map[string]fileEntry
+	// dirty is set when files changed and must be saved by Close
+	dirty  bool
+	hits   int
+	misses int
+}
+
+// fileEntry is the digest of a file, valid while its size and modification time match
+type fileEntry struct {
+	Size    int64  `json:"size"`
+	ModTime int64  `json:"modTime"`
+	Digest  string `json:"digest"`
+}
+
+// Open opens the cache in the given directory, creating it if needed
+func Open(dir string) (*Cache, error) {
+	if err := os.MkdirAll(filepath.Join(dir, entriesDir), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	c := &Cache{dir: dir, files: map[string]fileEntry{}}
+	data, err := os.ReadFile(filepath.Join(dir, indexFile))
+	if err == nil {
+		// A corrupt index only costs a rehash
+		if json.Unmarshal(data, &c.files) != nil {
+			c.files = map[string]fileEntry{}
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Dir returns the cache directory
+func (c *Cache) Dir() string {
+	return c.dir
+}
+
+// Get loads the entry of the given kind and digest into v and reports
+// whether it was found. Reading an entry marks it as recently used.
+func (c *Cache) Get(kind, digest string, v interface{}) bool {
+	if c == nil {
+		return false
+	}
+
+	path := c.entryPath(kind, digest)
+	data, err := os.ReadFile(path)
+	if err == nil && json.Unmarshal(data, v) == nil {
+		now := time.Now()
+		_ = os.Chtimes(path, now, now)
+		c.count(true)
+		return true
+	}
+	c.count(false)
+	return false
+}
+
+// Put stores v as the entry of the given kind and digest
+func (c *Cache) Put(kind, digest string, v interface{}) error {
+	if c == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	path := c.entryPath(kind, digest)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return writeAtomic(path, data)
+}
+
+// Stats returns the number of entries found and missed since the cache was opened
+func (c *Cache) Stats() (hits, misses int) {
+	if c == nil {
+		return 0, 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.hits, c.misses
+}
+
+// Close saves the file digests computed during the run
+func (c *Cache) Close() error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.dirty {
+		return nil
+	}
+
+	data, err := json.Marshal(c.files)
+	if err != nil {
+		return err
+	}
+	c.dirty = false
+	return writeAtomic(filepath.Join(c.dir, indexFile), data)
+}
+
+// entryPath returns the file of an entry, fanned out by digest prefix
+func (c *Cache) entryPath(kind, digest string) string {
+	prefix := digest
+	if len(prefix) > 2 {
+		prefix = prefix[:2]
+	}
+	return filepath.Join(c.dir, entriesDir, kind, prefix, digest+".json")
+}
+
+// count records a hit or a miss
+func (c *Cache) count(hit bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if hit {
+		c.hits++
+	} else {
+		c.misses++
+	}
+}
+
+// writeAtomic writes the file through a temporary file, so that concurrent
+// runs never read a partial entry
+func writeAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
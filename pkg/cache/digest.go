@@ -0,0 +1,127 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Digest returns the content digest of the regular files below root, using
+// their slash separated relative paths and contents. Directories for which
+// skip returns true are left out. Files whose size and modification time are
+// unchanged since a previous run are not read again. A root that is a
+// symbolic link, such as a symlinked subchart, is resolved first so that the
+// files of its target are digested.
+func (c *Cache) Digest(root string, skip func(rel string) bool) (string, error) {
+	root, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return "", err
+	}
+
+	type entry struct {
+		rel    string
+		digest string
+	}
+	var entries []entry
+
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if d.IsDir() {
+			if rel != "." && skip != nil && skip(rel) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		digest, err := c.fileDigest(path, info)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, entry{rel: rel, digest: digest})
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].rel < entries[j].rel })
+	h := sha256.New()
+	for _, e := range entries {
+		fmt.Fprintf(h, "%s\x00%s\n", e.rel, e.digest)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Combine returns the digest of a list of strings, e.g. digests and settings
+func Combine(parts ...string) string {
+	h := sha256.New()
+	for _, p := range parts {
+		fmt.Fprintf(h, "%d:%s\n", len(p), p)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// FileDigest returns the content digest of a single file
+func (c *Cache) FileDigest(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	return c.fileDigest(path, info)
+}
+
+// fileDigest returns the digest of a file, reusing the one recorded for an
+// unchanged file
+func (c *Cache) fileDigest(path string, info fs.FileInfo) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+
+	if c != nil {
+		c.mu.Lock()
+		cached, ok := c.files[abs]
+		c.mu.Unlock()
+		if ok && cached.Size == info.Size() && cached.ModTime == info.ModTime().UnixNano() {
+			return cached.Digest, nil
+		}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	digest := hex.EncodeToString(h.Sum(nil))
+
+	if c != nil {
+		c.mu.Lock()
+		c.files[abs] = fileEntry{Size: info.Size(), ModTime: info.ModTime().UnixNano(), Digest: digest}
+		c.dirty = true
+		c.mu.Unlock()
+	}
+	return digest, nil
+}
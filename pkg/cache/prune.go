@@ -0,0 +1,107 @@
+package cache
+
+import (
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// PruneResult describes what a prune removed
+type PruneResult struct {
+	// Entries is the number of analysis results removed
+	Entries int
+	// Files is the number of file digests dropped from the index
+	Files int
+	// Freed is the size of the removed entries in bytes
+	Freed int64
+}
+
+// Prune removes the entries not used within maxAge, or every entry when
+// maxAge is zero, and drops the digests of files that changed or no longer exist
+func Prune(dir string, maxAge time.Duration, dryRun bool) (*PruneResult, error) {
+	result := &PruneResult{}
+	cutoff := time.Now().Add(-maxAge)
+
+	root := filepath.Join(dir, entriesDir)
+	var dirs []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == root {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if d.IsDir() && path != root {
+			dirs = append(dirs, path)
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if maxAge > 0 && info.ModTime().After(cutoff) {
+			return nil
+		}
+		result.Entries++
+		result.Freed += info.Size()
+		if dryRun {
+			return nil
+		}
+		return os.Remove(path)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Remove the directories left empty, deepest first; others fail and stay
+	if !dryRun {
+		for i := len(dirs) - 1; i >= 0; i-- {
+			_ = os.Remove(dirs[i])
+		}
+	}
+
+	if err := pruneIndex(dir, maxAge == 0, dryRun, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// pruneIndex drops the file digests that are stale, or all of them
+func pruneIndex(dir string, all, dryRun bool, result *PruneResult) error {
+	path := filepath.Join(dir, indexFile)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	files := map[string]fileEntry{}
+	_ = json.Unmarshal(data, &files)
+	for file, entry := range files {
+		info, err := os.Stat(file)
+		if all || err != nil || info.Size() != entry.Size || info.ModTime().UnixNano() != entry.ModTime {
+			delete(files, file)
+			result.Files++
+		}
+	}
+	if dryRun || result.Files == 0 {
+		return nil
+	}
+
+	if len(files) == 0 {
+		result.Freed += int64(len(data))
+		return os.Remove(path)
+	}
+	out, err := json.Marshal(files)
+	if err != nil {
+		return err
+	}
+	result.Freed += int64(len(data) - len(out))
+	return writeAtomic(path, out)
+}
@@ -1,12 +1,15 @@
 package cleanup
 
 import (
+	"errors"
 	"fmt"
 	"io/ioutil"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"strings"
 
+	"github.com/harness/helm-optimize/pkg/common"
 	"gopkg.in/yaml.v3"
 )
 
@@ -14,13 +17,25 @@ import (
 type Cleaner struct {
 	opts         Options
 	deletedPaths []string
+	// protectedPaths holds file dependency directories kept because they are protected
+	protectedPaths []common.ProtectedPath
+	// selector decides which paths may be traversed and deleted
+	selector common.Selector
+	// root is the absolute path of the root chart
+	root string
+	// traversal detects symlink cycles in the chart tree
+	traversal *common.Traversal
 }
 
 // NewCleaner creates a new Cleaner instance
 func NewCleaner(opts Options) *Cleaner {
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
 	return &Cleaner{
 		opts:         opts,
 		deletedPaths: []string{},
+		traversal:    common.NewTraversal(),
 	}
 }
 
@@ -31,6 +46,24 @@ func (c *Cleaner) Cleanup() error {
 		return fmt.Errorf("failed to get absolute path: %w", err)
 	}
 
+	c.opts.Logger.Debug("starting cleanup", "chart", c.opts.ChartPath)
+	if _, err := os.Stat(filepath.Join(chartPath, "Chart.yaml")); err != nil {
+		return fmt.Errorf("%w: no Chart.yaml in '%s'", common.ErrChartNotFound, c.opts.ChartPath)
+	}
+	c.root = chartPath
+
+	if err := common.ValidateSymlinkPolicy(c.opts.Symlinks); err != nil {
+		return err
+	}
+
+	c.selector = common.Selector{
+		Root:        chartPath,
+		Include:     c.opts.Include,
+		Exclude:     c.opts.Exclude,
+		NeverDelete: c.opts.NeverDelete,
+		Symlinks:    c.opts.Symlinks,
+	}
+
 	// Start DFS traversal from the root chart
 	if err := c.processChart(chartPath); err != nil {
 		return err
@@ -44,17 +77,27 @@ func (c *Cleaner) Cleanup() error {
 		}
 	}
 
+	if len(c.protectedPaths) > 0 {
+		fmt.Println("Protected directories (kept):")
+		for _, p := range c.protectedPaths {
+			fmt.Printf("  %s (%s)\n", p.Path, p.Reason)
+		}
+	}
+
 	if c.opts.DryRun {
-		fmt.Println("Dry run completed. No changes were made.")
-	} else if len(c.deletedPaths) > 0 {
-		fmt.Printf("Cleanup completed. Removed %d unnecessary directories.\n", len(c.deletedPaths))
+		c.opts.Logger.Info("dry run completed, no changes were made", "chart", c.opts.ChartPath)
 	} else {
-		fmt.Println("Cleanup completed. No unnecessary directories were found.")
+		c.opts.Logger.Info("cleanup completed", "chart", c.opts.ChartPath, "removed", len(c.deletedPaths))
 	}
 
 	return nil
 }
 
+// DeletedPaths returns the directories removed, or that would be removed in dry-run mode
+func (c *Cleaner) DeletedPaths() []string {
+	return c.deletedPaths
+}
+
 // processChart recursively processes a chart and its dependencies
 func (c *Cleaner) processChart(chartPath string) error {
 	// Check if this is a valid chart directory
@@ -63,10 +106,19 @@ func (c *Cleaner) processChart(chartPath string) error {
 		return nil // Not a chart, skip
 	}
 
-	// First, process this chart
-	if c.opts.Verbose {
-		fmt.Printf("Processing chart: %s\n", chartPath)
+	// A symlinked subchart linking back to one of its parents closes a cycle
+	entered, err := c.traversal.Enter(chartPath)
+	if err != nil {
+		return err
 	}
+	if !entered {
+		c.opts.Logger.Warn("symlink cycle detected, skipping", "chart", chartPath)
+		return nil
+	}
+	defer c.traversal.Leave(chartPath)
+
+	// First, process this chart
+	c.opts.Logger.Debug("processing chart", "chart", chartPath)
 
 	// Cleanup any file dependencies in the current Chart.yaml
 	if err := c.cleanupFileDependencies(chartPath); err != nil {
@@ -77,14 +129,18 @@ func (c *Cleaner) processChart(chartPath string) error {
 	chartsDir := filepath.Join(chartPath, "charts")
 	if _, err := os.Stat(chartsDir); err == nil {
 		// List all subdirectories in charts/
-		files, err := ioutil.ReadDir(chartsDir)
+		files, err := os.ReadDir(chartsDir)
 		if err != nil {
 			return fmt.Errorf("failed to read charts directory: %w", err)
 		}
 
 		for _, file := range files {
-			if file.IsDir() {
+			if common.IsDirOrLink(chartsDir, file, c.opts.Symlinks) {
 				subchartPath := filepath.Join(chartsDir, file.Name())
+				if c.selector.Excluded(subchartPath) {
+					c.opts.Logger.Debug("skipping excluded chart", "chart", subchartPath)
+					continue
+				}
 				if err := c.processChart(subchartPath); err != nil {
 					return err
 				}
@@ -113,11 +169,15 @@ func (c *Cleaner) cleanupFileDependencies(chartPath string) error {
 	}
 
 	if err := yaml.Unmarshal(data, &chart); err != nil {
-		return fmt.Errorf("failed to parse Chart.yaml: %w", err)
+		return fmt.Errorf("%w: failed to parse %s: %w", common.ErrInvalidChart, chartFile, err)
 	}
 
 	// Process each dependency
 	for _, dep := range chart.Dependencies {
+		if err := common.ValidateDependencyName(dep.Name); err != nil {
+			return fmt.Errorf("%s: %w", chartFile, err)
+		}
+
 		// Check if this is a file: repository
 		if !strings.HasPrefix(dep.Repository, "file:") {
 			continue
@@ -142,11 +202,21 @@ func (c *Cleaner) cleanupFileDependencies(chartPath string) error {
 			return fmt.Errorf("failed to get absolute path: %w", err)
 		}
 
+		// Never delete anything outside the chart being optimized, e.g. a
+		// sibling chart referenced as file://../common; such a dependency is
+		// kept and reported, in dry runs too
+		if err := common.Confine(c.root, originalDirPath); err != nil {
+			if !errors.Is(err, common.ErrUnsafePath) {
+				return err
+			}
+			c.opts.Logger.Debug("skipping file dependency outside the chart root", "dependency", dep.Name, "chart", chartFile, "path", originalDirPath)
+			c.protectedPaths = append(c.protectedPaths, common.ProtectedPath{Path: originalDirPath, Reason: "unsafe path: outside the chart root"})
+			continue
+		}
+
 		// Check if the directory exists
 		if _, err := os.Stat(originalDirPath); os.IsNotExist(err) {
-			if c.opts.Verbose {
-				fmt.Printf("Directory %s does not exist, skipping\n", originalDirPath)
-			}
+			c.opts.Logger.Debug("file dependency directory does not exist, skipping", "path", originalDirPath)
 			continue
 		}
 
@@ -155,19 +225,22 @@ func (c *Cleaner) cleanupFileDependencies(chartPath string) error {
 		// as they are part of the chart structure and should be preserved
 		parentDir := filepath.Base(filepath.Dir(originalDirPath))
 		if parentDir == "charts" {
-			if c.opts.Verbose {
-				fmt.Printf("Skipping directory %s - it's under charts/ directory (preserving chart structure)\n", originalDirPath)
-			}
+			c.opts.Logger.Debug("skipping directory under charts/, preserving chart structure", "path", originalDirPath)
 			continue
 		}
 
-		// Directory exists and does NOT have 'charts' as immediate parent, remove it
-		if c.opts.Verbose || c.opts.ShowDeleted {
-			fmt.Printf("Found file dependency directory: %s\n", originalDirPath)
+		// Honor keep/exclude patterns and chart annotations
+		if reason := c.selector.Protection(originalDirPath); reason != "" {
+			c.opts.Logger.Debug("skipping protected directory", "path", originalDirPath, "reason", reason)
+			c.protectedPaths = append(c.protectedPaths, common.ProtectedPath{Path: originalDirPath, Reason: reason})
+			continue
 		}
 
+		// Directory exists and does NOT have 'charts' as immediate parent, remove it
+		c.opts.Logger.Debug("found file dependency directory", "path", originalDirPath)
+
 		if !c.opts.DryRun {
-			if err := os.RemoveAll(originalDirPath); err != nil {
+			if err := common.RemoveAll(c.root, originalDirPath); err != nil {
 				return fmt.Errorf("failed to remove directory %s: %w", originalDirPath, err)
 			}
 			c.deletedPaths = append(c.deletedPaths, originalDirPath)
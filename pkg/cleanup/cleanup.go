@@ -1,7 +1,10 @@
 package cleanup
 
 import (
-	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/harness/helm-optimize/pkg/common"
 )
 
 // Options represents the configuration options for the cleanup operation
@@ -9,15 +12,37 @@ type Options struct {
 	ChartPath   string
 	DryRun      bool
 	ShowDeleted bool
-	Verbose     bool
+	// Check fails with common.ErrNotOptimized when directories would be removed; implies DryRun
+	Check bool
+	// Logger receives the diagnostics; slog.Default() when nil
+	Logger *slog.Logger
+	// Include limits deletion to paths matching these globs
+	Include []string
+	// Exclude skips chart paths matching these globs
+	Exclude []string
+	// NeverDelete protects paths matching these globs from deletion
+	NeverDelete []string
+	// Symlinks is the policy for symlinked charts: skip, follow or preserve
+	Symlinks string
 }
 
 // Run executes the cleanup operation with the given options
 func Run(opts Options) error {
-	if opts.Verbose {
-		fmt.Printf("Starting cleanup of chart at %s\n", opts.ChartPath)
+	if opts.Check {
+		opts.DryRun = true
 	}
 
 	cleaner := NewCleaner(opts)
-	return cleaner.Cleanup()
+	if err := cleaner.Cleanup(); err != nil {
+		return err
+	}
+
+	if opts.Check {
+		var changes []common.Change
+		for _, path := range cleaner.DeletedPaths() {
+			changes = append(changes, common.Change{Action: common.ActionDelete, Path: path})
+		}
+		return common.CheckChanges(os.Stdout, "cleanup", opts.ChartPath, changes)
+	}
+	return nil
 }
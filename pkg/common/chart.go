@@ -0,0 +1,44 @@
+package common
+
+import (
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Chart annotations understood by helm-optimize
+const (
+	// AnnotationKeep protects a chart from being deleted by any optimization
+	AnnotationKeep = "helm-optimize/keep"
+	// AnnotationExclude removes a chart and its subcharts from optimization
+	AnnotationExclude = "helm-optimize/exclude"
+)
+
+// ChartMetadata holds the Chart.yaml fields shared across optimizations
+type ChartMetadata struct {
+	Name        string            `yaml:"name"`
+	Version     string            `yaml:"version"`
+	Annotations map[string]string `yaml:"annotations"`
+}
+
+// ReadChartMetadata reads the Chart.yaml of the chart directory.
+// It returns nil when the directory holds no readable Chart.yaml.
+func ReadChartMetadata(chartPath string) *ChartMetadata {
+	data, err := os.ReadFile(filepath.Join(chartPath, "Chart.yaml"))
+	if err != nil {
+		return nil
+	}
+
+	var meta ChartMetadata
+	if err := yaml.Unmarshal(data, &meta); err != nil {
+		return nil
+	}
+
+	return &meta
+}
+
+// HasAnnotation reports whether the chart sets the annotation to "true"
+func (m *ChartMetadata) HasAnnotation(name string) bool {
+	return m != nil && m.Annotations[name] == "true"
+}
@@ -0,0 +1,40 @@
+package common
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+)
+
+// Actions of the changes reported by check mode
+const (
+	ActionDelete  = "delete"
+	ActionRewrite = "rewrite"
+)
+
+// Change is a path an optimization would delete or rewrite
+type Change struct {
+	Action string
+	Path   string
+}
+
+// CheckChanges implements check mode: it lists the changes an optimization
+// would make, one per line like 'gofmt -l', with paths relative to the chart
+// root, and returns ErrNotOptimized when there is any
+func CheckChanges(w io.Writer, optimization, root string, changes []Change) error {
+	if len(changes) == 0 {
+		fmt.Fprintf(w, "Check passed: %s would not change the chart.\n", optimization)
+		return nil
+	}
+
+	absRoot, _ := filepath.Abs(root)
+	for _, c := range changes {
+		path := c.Path
+		abs, _ := filepath.Abs(c.Path)
+		if rel, err := filepath.Rel(absRoot, abs); err == nil {
+			path = filepath.ToSlash(rel)
+		}
+		fmt.Fprintf(w, "would %s %s\n", c.Action, path)
+	}
+	return fmt.Errorf("%w: %s would change %d paths", ErrNotOptimized, optimization, len(changes))
+}
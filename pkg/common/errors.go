@@ -0,0 +1,21 @@
+package common
+
+import "errors"
+
+// Error categories shared by the commands. Errors wrap them with %w so that
+// callers, and the exit code of the plugin, can tell failures apart with
+// errors.Is.
+var (
+	// ErrChartNotFound reports a chart path that does not exist or holds no Chart.yaml
+	ErrChartNotFound = errors.New("chart not found")
+	// ErrInvalidChart reports a Chart.yaml that cannot be parsed or a chart Helm cannot load
+	ErrInvalidChart = errors.New("invalid chart")
+	// ErrUnsafePath reports a path outside the chart that an optimization would change
+	ErrUnsafePath = errors.New("unsafe path")
+	// ErrVerificationFailed reports an optimized chart or archive failing a check
+	ErrVerificationFailed = errors.New("verification failed")
+	// ErrPartialFailure reports a multi-chart run in which only some charts failed
+	ErrPartialFailure = errors.New("partial failure")
+	// ErrNotOptimized reports, in check mode, a chart an optimization would change
+	ErrNotOptimized = errors.New("chart is not optimized")
+)
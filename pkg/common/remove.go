@@ -0,0 +1,74 @@
+package common
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ValidateDependencyName rejects dependency names that cannot be a single
+// directory below charts/, e.g. "../../etc", so that paths built from a
+// Chart.yaml never leave the chart
+func ValidateDependencyName(name string) error {
+	switch {
+	case name == "":
+		return fmt.Errorf("%w: dependency without a name", ErrInvalidChart)
+	case name == "." || name == "..",
+		strings.ContainsAny(name, `/\`),
+		strings.ContainsRune(name, 0),
+		filepath.IsAbs(name),
+		filepath.VolumeName(name) != "":
+		return fmt.Errorf("%w: invalid dependency name %q", ErrUnsafePath, name)
+	}
+	return nil
+}
+
+// Confine checks that removing path only deletes something strictly below
+// root. Symbolic links are resolved: the entry removed is the last path
+// element in the real directory of its parent, so a link itself may be
+// removed but nothing is deleted through a link pointing outside the root.
+func Confine(root, path string) error {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return err
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	if !pathBelow(absPath, absRoot) {
+		return fmt.Errorf("%w: %s is not below the chart root %s", ErrUnsafePath, path, root)
+	}
+
+	realRoot, err := filepath.EvalSymlinks(absRoot)
+	if err != nil {
+		return err
+	}
+	parent, err := filepath.EvalSymlinks(filepath.Dir(absPath))
+	if err != nil {
+		if os.IsNotExist(err) {
+			// Nothing left to delete
+			return nil
+		}
+		return err
+	}
+	if real := filepath.Join(parent, filepath.Base(absPath)); !pathBelow(real, realRoot) {
+		return fmt.Errorf("%w: %s resolves to %s outside the chart root %s", ErrUnsafePath, path, real, root)
+	}
+	return nil
+}
+
+// RemoveAll removes path and everything below it after checking with
+// Confine that the deletion stays within root
+func RemoveAll(root, path string) error {
+	if err := Confine(root, path); err != nil {
+		return err
+	}
+	return os.RemoveAll(path)
+}
+
+// pathBelow reports whether the path lies strictly below the directory
+func pathBelow(path, dir string) bool {
+	return path != dir && pathWithin(path, dir)
+}
@@ -0,0 +1,109 @@
+package common
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Selector decides which subchart paths an optimization may touch.
+// Patterns containing a "/" are matched against paths relative to the root
+// chart using MatchGlob; patterns without one are also matched against the
+// chart name, so "redis*" protects every redis subchart wherever it lives.
+type Selector struct {
+	// Root is the root chart directory the patterns are relative to
+	Root string
+	// Include limits optimizations to matching paths when non-empty
+	Include []string
+	// Exclude removes matching paths (and everything below them) from optimization
+	Exclude []string
+	// NeverDelete lists paths that may be traversed but must never be removed
+	NeverDelete []string
+	// Symlinks is the symlink policy; empty selects SymlinksFollow
+	Symlinks string
+}
+
+// ProtectedPath records a path that was kept because it is protected
+type ProtectedPath struct {
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+}
+
+// Rel returns the slash separated path of p relative to the selector root
+func (s Selector) Rel(p string) string {
+	rel, err := filepath.Rel(s.Root, p)
+	if err != nil {
+		return filepath.ToSlash(p)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// Excluded reports whether the path should be skipped entirely
+func (s Selector) Excluded(p string) bool {
+	if s.Symlinks == SymlinksSkip && s.ViaSymlink(p) {
+		return true
+	}
+	meta := ReadChartMetadata(p)
+	if _, ok := matchPathOrName(s.Exclude, s.Rel(p), chartName(p, meta)); ok {
+		return true
+	}
+	return meta.HasAnnotation(AnnotationExclude)
+}
+
+// Protection returns why the path is protected from deletion,
+// or an empty string when it may be deleted
+func (s Selector) Protection(p string) string {
+	rel := s.Rel(p)
+	meta := ReadChartMetadata(p)
+	name := chartName(p, meta)
+
+	if reason := s.symlinkProtection(p); reason != "" {
+		return reason
+	}
+	if pattern, ok := matchPathOrName(s.Exclude, rel, name); ok {
+		return fmt.Sprintf("excluded by pattern %q", pattern)
+	}
+	if pattern, ok := matchPathOrName(s.NeverDelete, rel, name); ok {
+		return fmt.Sprintf("kept by pattern %q", pattern)
+	}
+	if meta.HasAnnotation(AnnotationKeep) {
+		return fmt.Sprintf("kept by annotation %s", AnnotationKeep)
+	}
+	if meta.HasAnnotation(AnnotationExclude) {
+		return fmt.Sprintf("excluded by annotation %s", AnnotationExclude)
+	}
+	if len(s.Include) > 0 && !MatchAny(s.Include, rel) {
+		return "not matched by include patterns"
+	}
+	return ""
+}
+
+// CanDelete reports whether the path is eligible for deletion
+func (s Selector) CanDelete(p string) bool {
+	return s.Protection(p) == ""
+}
+
+// chartName returns the chart name from its metadata, falling back to the directory name
+func chartName(p string, meta *ChartMetadata) string {
+	if meta != nil && meta.Name != "" {
+		return meta.Name
+	}
+	return filepath.Base(p)
+}
+
+// matchPathOrName returns the first pattern matching either the relative path
+// or, for patterns without a "/", the chart name
+func matchPathOrName(patterns []string, rel, name string) (string, bool) {
+	for _, pattern := range patterns {
+		if MatchGlob(pattern, rel) {
+			return pattern, true
+		}
+		if !strings.Contains(pattern, "/") {
+			if ok, _ := path.Match(pattern, name); ok {
+				return pattern, true
+			}
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,135 @@
+package common
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Symlink policies deciding how symlinked subcharts are handled during traversal
+const (
+	// SymlinksSkip ignores symlinked subcharts: they are neither analyzed nor deleted
+	SymlinksSkip = "skip"
+	// SymlinksFollow traverses symlinked subcharts. A link may be removed,
+	// but nothing is deleted through it outside the chart root.
+	SymlinksFollow = "follow"
+	// SymlinksPreserve traverses symlinked subcharts but never removes a link
+	// or anything reached through one
+	SymlinksPreserve = "preserve"
+)
+
+// KnownSymlinkPolicies lists the supported symlink policies
+var KnownSymlinkPolicies = []string{SymlinksSkip, SymlinksFollow, SymlinksPreserve}
+
+// ValidateSymlinkPolicy checks that the policy is known; empty selects SymlinksFollow
+func ValidateSymlinkPolicy(policy string) error {
+	if policy == "" {
+		return nil
+	}
+	for _, known := range KnownSymlinkPolicies {
+		if policy == known {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown symlink policy '%s' (known: %s)", policy, strings.Join(KnownSymlinkPolicies, ", "))
+}
+
+// ViaSymlink reports whether the path, or one of its parents below the
+// selector root, is a symbolic link
+func (s Selector) ViaSymlink(p string) bool {
+	rel, err := filepath.Rel(s.Root, p)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return false
+	}
+	current := s.Root
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		current = filepath.Join(current, part)
+		info, err := os.Lstat(current)
+		if err != nil {
+			return false
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// symlinkProtection returns why deleting a path reached through a symbolic
+// link is refused, or an empty string when it may be deleted
+func (s Selector) symlinkProtection(p string) string {
+	if !s.ViaSymlink(p) {
+		return ""
+	}
+	if s.Symlinks == SymlinksSkip || s.Symlinks == SymlinksPreserve {
+		return fmt.Sprintf("symlinked chart preserved by the %q symlink policy", s.Symlinks)
+	}
+
+	// Removing the path deletes the directory entry in its resolved parent:
+	// the link itself when the path is a link, its target's content otherwise
+	parent, err := filepath.EvalSymlinks(filepath.Dir(p))
+	if err != nil {
+		return fmt.Sprintf("cannot resolve symlink: %v", err)
+	}
+	root, err := filepath.EvalSymlinks(s.Root)
+	if err != nil {
+		return fmt.Sprintf("cannot resolve chart root: %v", err)
+	}
+	if !pathWithin(filepath.Join(parent, filepath.Base(p)), root) {
+		return "symlink target outside the chart root"
+	}
+	return ""
+}
+
+// Traversal detects symlink cycles while walking a chart tree: a directory
+// whose resolved path is already being traversed closes a cycle
+type Traversal struct {
+	active map[string]bool
+}
+
+// NewTraversal creates an empty Traversal
+func NewTraversal() *Traversal {
+	return &Traversal{active: map[string]bool{}}
+}
+
+// Enter marks the directory as being traversed. It returns false when the
+// directory closes a cycle and must not be traversed again. Every entered
+// directory must be left with Leave.
+func (t *Traversal) Enter(dir string) (bool, error) {
+	real, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return false, err
+	}
+	if t.active[real] {
+		return false, nil
+	}
+	t.active[real] = true
+	return true, nil
+}
+
+// Leave marks the directory as no longer being traversed
+func (t *Traversal) Leave(dir string) {
+	if real, err := filepath.EvalSymlinks(dir); err == nil {
+		delete(t.active, real)
+	}
+}
+
+// IsDirOrLink reports whether a directory entry is a directory, or a symbolic
+// link to one when the policy traverses symlinks
+func IsDirOrLink(dir string, entry os.DirEntry, policy string) bool {
+	if entry.IsDir() {
+		return true
+	}
+	if entry.Type()&os.ModeSymlink == 0 || policy == SymlinksSkip {
+		return false
+	}
+	info, err := os.Stat(filepath.Join(dir, entry.Name()))
+	return err == nil && info.IsDir()
+}
+
+// pathWithin reports whether the path is, or lies below, the directory
+func pathWithin(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
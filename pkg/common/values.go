@@ -0,0 +1,21 @@
+package common
+
+import (
+	"fmt"
+
+	"helm.sh/helm/v3/pkg/chartutil"
+)
+
+// LoadValueFiles reads and merges values files the way 'helm install -f' does:
+// values from later files take precedence over earlier ones
+func LoadValueFiles(files []string) (map[string]interface{}, error) {
+	merged := map[string]interface{}{}
+	for _, file := range files {
+		vals, err := chartutil.ReadValuesFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read values file %s: %w", file, err)
+		}
+		merged = chartutil.CoalesceTables(vals.AsMap(), merged)
+	}
+	return merged, nil
+}
@@ -0,0 +1,169 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultFileName is the configuration file discovered at the chart root
+const DefaultFileName = ".helm-optimize.yaml"
+
+// Names of the optimizations that can be enabled in the configuration file
+const (
+	OptimizationCleanup = "cleanup"
+	OptimizationDedup   = "dedup"
+	OptimizationStrip   = "strip"
+	// OptimizationPruneDisabled removes subcharts disabled in every values set
+	OptimizationPruneDisabled = "prune-disabled"
+)
+
+// KnownOptimizations lists the supported optimizations. 'run' executes the
+// configured ones in the order of the configuration file.
+var KnownOptimizations = []string{
+	OptimizationCleanup,
+	OptimizationPruneDisabled,
+	OptimizationDedup,
+	OptimizationStrip,
+}
+
+// Config represents the structure of a .helm-optimize.yaml file
+type Config struct {
+	// Optimizations lists the optimizations executed by 'helm optimize run'
+	Optimizations []string `yaml:"optimizations"`
+	// Charts selects which subcharts the optimizations may touch
+	Charts ChartsConfig `yaml:"charts"`
+	// NeverDelete lists subchart paths that must never be removed
+	NeverDelete []string `yaml:"neverDelete"`
+	// Dedup holds the defaults for the dedup command
+	Dedup DedupConfig `yaml:"dedup"`
+	// Cleanup holds the defaults for the cleanup command
+	Cleanup CleanupConfig `yaml:"cleanup"`
+	// PruneDisabled holds the values sets used by the prune-disabled optimization
+	PruneDisabled PruneDisabledConfig `yaml:"pruneDisabled"`
+	// Strip holds the rule sets used by the strip optimization
+	Strip []StripRuleSet `yaml:"strip"`
+	// Verify holds the checks executed after the optimizations
+	Verify VerifyConfig `yaml:"verify"`
+
+	// path is the file the configuration was loaded from, empty for defaults
+	path string
+}
+
+// ChartsConfig holds the include/exclude globs for subchart paths
+type ChartsConfig struct {
+	Include []string `yaml:"include"`
+	Exclude []string `yaml:"exclude"`
+	// Symlinks is the policy for symlinked subcharts: skip, follow or preserve
+	Symlinks string `yaml:"symlinks"`
+}
+
+// DedupConfig holds the configurable dedup settings
+type DedupConfig struct {
+	Output      string `yaml:"output"`
+	Package     bool   `yaml:"package"`
+	DryRun      bool   `yaml:"dryRun"`
+	ShowDeleted bool   `yaml:"showDeleted"`
+	// KeepStrategy selects which copy of a duplicate dependency is kept
+	KeepStrategy string `yaml:"keepStrategy"`
+	// Prefer lists the subchart path globs kept by the explicit strategy
+	Prefer []string `yaml:"prefer"`
+	// ValuesCheck handles copies receiving different values: off, warn or refuse
+	ValuesCheck string `yaml:"valuesCheck"`
+	// Values lists values files applied on top of the root chart values
+	Values []string `yaml:"values"`
+	// Push uploads the packaged chart to an OCI repository, e.g. oci://host/repo
+	Push string `yaml:"push"`
+	// PlainHTTP talks to the push registry over HTTP
+	PlainHTTP bool `yaml:"plainHTTP"`
+}
+
+// CleanupConfig holds the configurable cleanup settings
+type CleanupConfig struct {
+	DryRun      bool `yaml:"dryRun"`
+	ShowDeleted bool `yaml:"showDeleted"`
+}
+
+// PruneDisabledConfig holds the prune-disabled settings
+type PruneDisabledConfig struct {
+	// Values lists the values sets; each entry is a comma separated list of files
+	Values []string `yaml:"values"`
+}
+
+// StripRuleSet is a named list of file globs removed from every chart in the tree
+type StripRuleSet struct {
+	Name     string   `yaml:"name"`
+	Patterns []string `yaml:"patterns"`
+}
+
+// VerifyConfig holds the verification settings
+type VerifyConfig struct {
+	// Load checks that the optimized chart can still be loaded by Helm
+	Load bool `yaml:"load"`
+}
+
+// Path returns the file the configuration was loaded from
+func (c *Config) Path() string {
+	return c.path
+}
+
+// Enabled reports whether the named optimization is listed in the configuration
+func (c *Config) Enabled(name string) bool {
+	for _, o := range c.Optimizations {
+		if o == name {
+			return true
+		}
+	}
+	return false
+}
+
+// StripPatterns returns the patterns of all strip rule sets
+func (c *Config) StripPatterns() []string {
+	var patterns []string
+	for _, rs := range c.Strip {
+		patterns = append(patterns, rs.Patterns...)
+	}
+	return patterns
+}
+
+// Load reads and parses the configuration file at the given path
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &Config{}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	cfg.path = path
+
+	return cfg, nil
+}
+
+// Discover loads the configuration for a chart. An explicit path takes
+// precedence; otherwise DefaultFileName is looked up at the chart root.
+// When no file is found, an empty configuration is returned.
+func Discover(chartPath, explicitPath string) (*Config, error) {
+	if explicitPath != "" {
+		return Load(explicitPath)
+	}
+
+	candidate := filepath.Join(chartPath, DefaultFileName)
+	if _, err := os.Stat(candidate); err != nil {
+		if os.IsNotExist(err) {
+			return &Config{}, nil
+		}
+		return nil, err
+	}
+
+	return Load(candidate)
+}
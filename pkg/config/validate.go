@@ -0,0 +1,99 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/harness/helm-optimize/pkg/common"
+	"github.com/harness/helm-optimize/pkg/dedup"
+)
+
+// Validate checks the configuration for unknown optimizations, invalid
+// globs and incomplete strip rule sets. All problems are reported at once.
+func (c *Config) Validate() error {
+	var errs []error
+
+	seen := map[string]bool{}
+	for _, o := range c.Optimizations {
+		if !isKnownOptimization(o) {
+			errs = append(errs, fmt.Errorf("optimizations: unknown optimization %q (known: %v)", o, KnownOptimizations))
+		}
+		if seen[o] {
+			errs = append(errs, fmt.Errorf("optimizations: %q listed more than once", o))
+		}
+		seen[o] = true
+	}
+
+	errs = append(errs, validateGlobs("charts.include", c.Charts.Include)...)
+	errs = append(errs, validateGlobs("charts.exclude", c.Charts.Exclude)...)
+	errs = append(errs, validateGlobs("neverDelete", c.NeverDelete)...)
+	if err := common.ValidateSymlinkPolicy(c.Charts.Symlinks); err != nil {
+		errs = append(errs, fmt.Errorf("charts.symlinks: %v", err))
+	}
+
+	if c.Dedup.KeepStrategy != "" && !contains(dedup.KnownStrategies, c.Dedup.KeepStrategy) {
+		errs = append(errs, fmt.Errorf("dedup.keepStrategy: unknown strategy %q (known: %v)", c.Dedup.KeepStrategy, dedup.KnownStrategies))
+	}
+	if c.Dedup.KeepStrategy == dedup.StrategyExplicit && len(c.Dedup.Prefer) == 0 {
+		errs = append(errs, fmt.Errorf("dedup.prefer: required by the %q keep strategy", dedup.StrategyExplicit))
+	}
+	errs = append(errs, validateGlobs("dedup.prefer", c.Dedup.Prefer)...)
+	if c.Dedup.ValuesCheck != "" && !contains(dedup.KnownValuesChecks, c.Dedup.ValuesCheck) {
+		errs = append(errs, fmt.Errorf("dedup.valuesCheck: unknown mode %q (known: %v)", c.Dedup.ValuesCheck, dedup.KnownValuesChecks))
+	}
+	if c.Dedup.Push != "" && !strings.HasPrefix(c.Dedup.Push, "oci://") {
+		errs = append(errs, fmt.Errorf("dedup.push: %q must start with oci://", c.Dedup.Push))
+	}
+
+	names := map[string]bool{}
+	for i, rs := range c.Strip {
+		field := fmt.Sprintf("strip[%d]", i)
+		if rs.Name == "" {
+			errs = append(errs, fmt.Errorf("%s: name is required", field))
+		} else if names[rs.Name] {
+			errs = append(errs, fmt.Errorf("%s: duplicate rule set name %q", field, rs.Name))
+		}
+		names[rs.Name] = true
+		if len(rs.Patterns) == 0 {
+			errs = append(errs, fmt.Errorf("%s: at least one pattern is required", field))
+		}
+		errs = append(errs, validateGlobs(field+".patterns", rs.Patterns)...)
+	}
+
+	if c.Enabled(OptimizationPruneDisabled) && len(c.PruneDisabled.Values) == 0 {
+		errs = append(errs, fmt.Errorf("pruneDisabled.values: required when %q is enabled", OptimizationPruneDisabled))
+	}
+
+	if c.Enabled(OptimizationStrip) && len(c.Strip) == 0 {
+		errs = append(errs, fmt.Errorf("optimizations: %q is enabled but no strip rule sets are defined", OptimizationStrip))
+	}
+
+	return errors.Join(errs...)
+}
+
+// validateGlobs checks every pattern of a config field
+func validateGlobs(field string, patterns []string) []error {
+	var errs []error
+	for _, pattern := range patterns {
+		if err := common.ValidateGlob(pattern); err != nil {
+			errs = append(errs, fmt.Errorf("%s: invalid pattern %q: %v", field, pattern, err))
+		}
+	}
+	return errs
+}
+
+// isKnownOptimization reports whether the name is a supported optimization
+func isKnownOptimization(name string) bool {
+	return contains(KnownOptimizations, name)
+}
+
+// contains reports whether the list holds the value
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,68 @@
+package crds
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/harness/helm-optimize/pkg/common"
+)
+
+// Directories of the CRD chart the definitions can be written to
+const (
+	// DirTemplates installs the CRDs as regular templates, so 'helm upgrade' updates them
+	DirTemplates = "templates"
+	// DirCRDs uses Helm's crds/ directory: installed once, never upgraded or deleted
+	DirCRDs = "crds"
+)
+
+// Options defines the parameters for extracting CRDs
+type Options struct {
+	ChartPath string
+	// OutputDir is the directory the CRD chart is created in (default: the chart's parent directory)
+	OutputDir string
+	// Name is the name of the CRD chart (default: <chart name>-crds)
+	Name string
+	// Dir is the directory of the CRD chart receiving the definitions: templates|crds
+	Dir         string
+	DryRun      bool
+	ShowDeleted bool
+	Verbose     bool
+	// Check fails with common.ErrNotOptimized when CRDs would be extracted; implies DryRun
+	Check bool
+	// Exclude skips subchart paths matching these globs or chart names
+	Exclude []string
+}
+
+// Run executes the CRD extraction with the given options
+func Run(opts Options) error {
+	if opts.Dir == "" {
+		opts.Dir = DirTemplates
+	}
+	if opts.Dir != DirTemplates && opts.Dir != DirCRDs {
+		return fmt.Errorf("unknown CRD chart directory '%s' (known: %s, %s)", opts.Dir, DirTemplates, DirCRDs)
+	}
+	if opts.OutputDir == "" {
+		chartPath, err := filepath.Abs(opts.ChartPath)
+		if err != nil {
+			return fmt.Errorf("failed to get absolute path: %w", err)
+		}
+		opts.OutputDir = filepath.Dir(chartPath)
+	}
+
+	if opts.Check {
+		opts.DryRun = true
+	}
+
+	fmt.Printf("Extracting CRDs of chart at '%s'...\n", opts.ChartPath)
+
+	extractor := NewExtractor(opts)
+	if err := extractor.Extract(); err != nil {
+		return err
+	}
+
+	if opts.Check {
+		return common.CheckChanges(os.Stdout, "extract-crds", opts.ChartPath, extractor.Changes())
+	}
+	return nil
+}
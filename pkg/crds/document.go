@@ -0,0 +1,154 @@
+package crds
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// kindCRD is the kind of a CustomResourceDefinition
+const kindCRD = "CustomResourceDefinition"
+
+// documentSeparator splits multi-document YAML files
+var documentSeparator = regexp.MustCompile(`(?m)^---[ \t]*(#.*)?$`)
+
+// templateDirective detects Go template actions in a document
+var templateDirective = regexp.MustCompile(`\{\{`)
+
+// literalCRD detects a CRD kind in a document that cannot be parsed
+var literalCRD = regexp.MustCompile(`(?m)^kind:\s*["']?` + kindCRD + `["']?\s*$`)
+
+// sourceFile is a YAML file of a chart split into its documents
+type sourceFile struct {
+	path      string
+	documents []string
+}
+
+// readSourceFile reads a YAML file and splits it into documents
+func readSourceFile(path string) (*sourceFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return &sourceFile{path: path, documents: documentSeparator.Split(string(data), -1)}, nil
+}
+
+// remaining returns the content left once the dropped documents are removed;
+// empty when the file would be removed
+func (f *sourceFile) remaining(drop map[int]bool) string {
+	var kept []string
+	for i, doc := range f.documents {
+		if drop[i] || strings.TrimSpace(doc) == "" {
+			continue
+		}
+		kept = append(kept, strings.Trim(doc, "\n"))
+	}
+
+	content := strings.TrimSpace(strings.Join(kept, "\n---\n"))
+	if isCommentOnly(content) {
+		return ""
+	}
+	return content
+}
+
+// write stores the documents that are not dropped, or removes the file when none remain
+func (f *sourceFile) write(drop map[int]bool) error {
+	content := f.remaining(drop)
+	if content == "" {
+		if err := os.Remove(f.path); err != nil {
+			return err
+		}
+		// Drop a crds/ directory left empty
+		dir := filepath.Dir(f.path)
+		if entries, err := os.ReadDir(dir); err == nil && len(entries) == 0 && filepath.Base(dir) == "crds" {
+			return os.Remove(dir)
+		}
+		return nil
+	}
+
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(f.path, []byte(content+"\n"), info.Mode().Perm())
+}
+
+// isCommentOnly reports whether the content holds nothing but YAML comments
+func isCommentOnly(content string) bool {
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			return false
+		}
+	}
+	return true
+}
+
+// definition is a parsed CustomResourceDefinition document
+type definition struct {
+	group    string
+	kind     string
+	plural   string
+	versions []string
+	// hash identifies the content, ignoring formatting, key order and status
+	hash    string
+	content string
+}
+
+// Name returns the object name of the CRD, <plural>.<group>
+func (d definition) Name() string {
+	return d.plural + "." + d.group
+}
+
+// Key identifies the resource defined by the CRD
+func (d definition) Key() string {
+	return d.group + "/" + d.kind
+}
+
+// parseDefinition parses a YAML document and returns the CRD it defines, or nil
+// when the document is not a CRD
+func parseDefinition(doc string) (*definition, error) {
+	var obj map[string]interface{}
+	if err := yaml.Unmarshal([]byte(doc), &obj); err != nil {
+		return nil, err
+	}
+	if kind, _ := obj["kind"].(string); kind != kindCRD {
+		return nil, nil
+	}
+
+	spec, _ := obj["spec"].(map[string]interface{})
+	names, _ := spec["names"].(map[string]interface{})
+	def := &definition{content: strings.Trim(doc, "\n") + "\n"}
+	def.group, _ = spec["group"].(string)
+	def.kind, _ = names["kind"].(string)
+	def.plural, _ = names["plural"].(string)
+
+	// apiextensions.k8s.io/v1 lists versions, v1beta1 may use a single version field
+	if versions, ok := spec["versions"].([]interface{}); ok {
+		for _, v := range versions {
+			if version, ok := v.(map[string]interface{}); ok {
+				if name, ok := version["name"].(string); ok {
+					def.versions = append(def.versions, name)
+				}
+			}
+		}
+	} else if version, ok := spec["version"].(string); ok {
+		def.versions = []string{version}
+	}
+
+	delete(obj, "status")
+	normalized, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(normalized)
+	def.hash = hex.EncodeToString(sum[:])
+
+	return def, nil
+}
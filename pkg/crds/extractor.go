@@ -0,0 +1,360 @@
+package crds
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/harness/helm-optimize/pkg/common"
+	"gopkg.in/yaml.v3"
+)
+
+// Extractor moves the CRDs of a chart tree into a standalone CRD chart
+type Extractor struct {
+	opts     Options
+	selector common.Selector
+	// files holds every scanned file containing a CRD, by path
+	files map[string]*sourceFile
+	// copies holds every CRD copy found, in traversal order
+	copies []Copy
+	// templated lists template documents defining a CRD that cannot be extracted
+	templated []string
+	// changes lists the source files the extraction rewrites or deletes
+	changes []common.Change
+}
+
+// Copy is a CRD found in a chart of the tree
+type Copy struct {
+	// Chart is the directory of the chart shipping the CRD
+	Chart string
+	// File is the file holding the CRD
+	File string
+	// Versions lists the served versions of the CRD
+	Versions []string
+	// Hash identifies the content of the CRD
+	Hash string
+
+	def      *definition
+	document int
+}
+
+// Extracted describes a CRD moved into the CRD chart
+type Extracted struct {
+	Name string
+	// File is the path of the CRD in the CRD chart
+	File string
+	// Copies lists the identical copies removed from the chart tree
+	Copies []Copy
+}
+
+// Conflict describes a CRD shipped in different versions or contents by several charts
+type Conflict struct {
+	Name   string
+	Copies []Copy
+}
+
+// NewExtractor creates a new Extractor
+func NewExtractor(opts Options) *Extractor {
+	return &Extractor{opts: opts, files: map[string]*sourceFile{}}
+}
+
+// Extract collects the CRDs of the chart tree, writes the CRD chart and removes
+// the extracted CRDs from the charts that shipped them
+func (e *Extractor) Extract() error {
+	chartPath, err := filepath.Abs(e.opts.ChartPath)
+	if err != nil {
+		return fmt.Errorf("failed to get absolute path: %w", err)
+	}
+	e.selector = common.Selector{Root: chartPath, Exclude: e.opts.Exclude}
+
+	metadata := common.ReadChartMetadata(chartPath)
+	if metadata == nil {
+		return fmt.Errorf("no Chart.yaml found in '%s'", e.opts.ChartPath)
+	}
+	name := e.opts.Name
+	if name == "" {
+		name = metadata.Name + "-crds"
+	}
+	crdChart := filepath.Join(e.opts.OutputDir, name)
+
+	if err := e.collect(chartPath); err != nil {
+		return err
+	}
+
+	extracted, conflicts := e.resolve(crdChart)
+	e.printReport(extracted, conflicts)
+	e.changes = e.plan(extracted)
+
+	if len(extracted) == 0 {
+		fmt.Println("No CRDs to extract.")
+		return nil
+	}
+
+	if e.opts.DryRun {
+		fmt.Printf("Dry run completed. %d CRDs would be extracted to %s.\n", len(extracted), crdChart)
+		return nil
+	}
+
+	if err := e.writeChart(crdChart, name, metadata, extracted); err != nil {
+		return err
+	}
+	if err := e.removeCopies(extracted); err != nil {
+		return err
+	}
+
+	fmt.Printf("Extraction completed. %d CRDs written to %s.\n", len(extracted), crdChart)
+	return nil
+}
+
+// collect scans the crds/ and templates/ directories of the chart and of every
+// unpacked subchart
+func (e *Extractor) collect(chartPath string) error {
+	if e.opts.Verbose {
+		fmt.Printf("Scanning %s for CRDs\n", chartPath)
+	}
+
+	for _, dir := range []string{"crds", "templates"} {
+		if err := e.scanDir(chartPath, filepath.Join(chartPath, dir), dir == "templates"); err != nil {
+			return err
+		}
+	}
+
+	entries, err := os.ReadDir(filepath.Join(chartPath, "charts"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	for _, entry := range entries {
+		subChartPath := filepath.Join(chartPath, "charts", entry.Name())
+		if !entry.IsDir() {
+			if e.opts.Verbose && strings.HasSuffix(entry.Name(), ".tgz") {
+				fmt.Printf("  Skipping packaged subchart %s\n", subChartPath)
+			}
+			continue
+		}
+		if e.selector.Excluded(subChartPath) {
+			if e.opts.Verbose {
+				fmt.Printf("  Skipping excluded subchart %s\n", subChartPath)
+			}
+			continue
+		}
+		if err := e.collect(subChartPath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scanDir records the CRD documents of the YAML files below dir. Template
+// documents are only considered when they contain no template actions.
+func (e *Extractor) scanDir(chartPath, dir string, templates bool) error {
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return nil
+	}
+
+	return filepath.WalkDir(dir, func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		ext := filepath.Ext(path)
+		if ext != ".yaml" && ext != ".yml" {
+			return nil
+		}
+
+		file, err := readSourceFile(path)
+		if err != nil {
+			return err
+		}
+
+		found := false
+		for i, doc := range file.documents {
+			if strings.TrimSpace(doc) == "" {
+				continue
+			}
+			if templates && templateDirective.MatchString(doc) {
+				if literalCRD.MatchString(doc) {
+					e.templated = append(e.templated, path)
+				}
+				continue
+			}
+
+			def, err := parseDefinition(doc)
+			if err != nil {
+				return fmt.Errorf("failed to parse %s: %w", path, err)
+			}
+			if def == nil {
+				continue
+			}
+			found = true
+			e.copies = append(e.copies, Copy{
+				Chart:    chartPath,
+				File:     path,
+				Versions: def.versions,
+				Hash:     def.hash,
+				def:      def,
+				document: i,
+			})
+			if e.opts.Verbose {
+				fmt.Printf("  Found CRD %s (%s) in %s\n", def.Name(), strings.Join(def.versions, ", "), path)
+			}
+		}
+		if found {
+			e.files[path] = file
+		}
+		return nil
+	})
+}
+
+// resolve groups the copies by group and kind. Groups whose copies are all
+// identical are extracted; groups with differing copies are conflicts and stay
+// in place.
+func (e *Extractor) resolve(crdChart string) ([]Extracted, []Conflict) {
+	var order []string
+	groups := map[string][]Copy{}
+	for _, c := range e.copies {
+		key := c.def.Key()
+		if _, ok := groups[key]; !ok {
+			order = append(order, key)
+		}
+		groups[key] = append(groups[key], c)
+	}
+
+	var extracted []Extracted
+	var conflicts []Conflict
+	for _, key := range order {
+		copies := groups[key]
+		name := copies[0].def.Name()
+
+		identical := true
+		for _, c := range copies[1:] {
+			if c.Hash != copies[0].Hash {
+				identical = false
+				break
+			}
+		}
+		if !identical {
+			conflicts = append(conflicts, Conflict{Name: name, Copies: copies})
+			continue
+		}
+
+		extracted = append(extracted, Extracted{
+			Name:   name,
+			File:   filepath.Join(crdChart, e.opts.Dir, name+".yaml"),
+			Copies: copies,
+		})
+	}
+	return extracted, conflicts
+}
+
+// printReport lists the extracted CRDs, the conflicts and the templated CRDs left in place
+func (e *Extractor) printReport(extracted []Extracted, conflicts []Conflict) {
+	for _, x := range extracted {
+		fmt.Printf("CRD %s (%s):\n", x.Name, strings.Join(x.Copies[0].Versions, ", "))
+		for _, c := range x.Copies {
+			fmt.Printf("  extracted from %s\n", c.File)
+		}
+	}
+	for _, conflict := range conflicts {
+		fmt.Printf("CONFLICT CRD %s is shipped in different versions, keeping every copy in place:\n", conflict.Name)
+		for _, c := range conflict.Copies {
+			fmt.Printf("  %s versions %s content %s\n", c.File, strings.Join(c.Versions, ", "), c.Hash[:12])
+		}
+	}
+	for _, path := range e.templated {
+		fmt.Printf("Skipped templated CRD in %s\n", path)
+	}
+}
+
+// writeChart creates the CRD chart holding one file per extracted CRD
+func (e *Extractor) writeChart(dir, name string, metadata *common.ChartMetadata, extracted []Extracted) error {
+	if _, err := os.Stat(dir); err == nil {
+		return fmt.Errorf("CRD chart directory '%s' already exists", dir)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, e.opts.Dir), 0755); err != nil {
+		return fmt.Errorf("failed to create CRD chart: %w", err)
+	}
+
+	chartYaml, err := yaml.Marshal(map[string]interface{}{
+		"apiVersion":  "v2",
+		"name":        name,
+		"description": fmt.Sprintf("CustomResourceDefinitions extracted from %s", metadata.Name),
+		"type":        "application",
+		"version":     metadata.Version,
+	})
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "Chart.yaml"), chartYaml, 0644); err != nil {
+		return fmt.Errorf("failed to write CRD chart: %w", err)
+	}
+
+	for _, x := range extracted {
+		if err := os.WriteFile(x.File, []byte(x.Copies[0].def.content), 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", x.File, err)
+		}
+	}
+	return nil
+}
+
+// dropped returns the documents to remove from every source file, by path,
+// and the sorted paths
+func dropped(extracted []Extracted) (map[string]map[int]bool, []string) {
+	drop := map[string]map[int]bool{}
+	for _, x := range extracted {
+		for _, c := range x.Copies {
+			if drop[c.File] == nil {
+				drop[c.File] = map[int]bool{}
+			}
+			drop[c.File][c.document] = true
+		}
+	}
+
+	var paths []string
+	for path := range drop {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return drop, paths
+}
+
+// plan lists the source files removing the extracted CRDs rewrites, or
+// deletes when no other document remains
+func (e *Extractor) plan(extracted []Extracted) []common.Change {
+	drop, paths := dropped(extracted)
+	var changes []common.Change
+	for _, path := range paths {
+		action := common.ActionRewrite
+		if e.files[path].remaining(drop[path]) == "" {
+			action = common.ActionDelete
+		}
+		changes = append(changes, common.Change{Action: action, Path: path})
+	}
+	return changes
+}
+
+// Changes returns the source files the extraction rewrites or deletes, or
+// would in dry-run mode
+func (e *Extractor) Changes() []common.Change {
+	return e.changes
+}
+
+// removeCopies removes the extracted CRD documents from their source files
+func (e *Extractor) removeCopies(extracted []Extracted) error {
+	drop, paths := dropped(extracted)
+	for _, path := range paths {
+		if e.opts.Verbose || e.opts.ShowDeleted {
+			fmt.Printf("Removing CRDs from %s\n", path)
+		}
+		if err := e.files[path].write(drop[path]); err != nil {
+			return fmt.Errorf("failed to update %s: %w", path, err)
+		}
+	}
+	return nil
+}
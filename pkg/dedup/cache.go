@@ -0,0 +1,223 @@
+package dedup
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/harness/helm-optimize/pkg/cache"
+	"github.com/harness/helm-optimize/pkg/common"
+)
+
+// cacheVersion is part of every cache key, so that entries written by an
+// incompatible version are never read
+const cacheVersion = "dedup-3"
+
+// Kinds of the cache entries written by deduplication
+const (
+	cacheKindChart  = "chart"
+	cacheKindValues = "values"
+)
+
+// chartSummary is the analysis of a chart directory without its subcharts
+type chartSummary struct {
+	// HasChartYaml is false for directories below charts/ that are not charts
+	HasChartYaml bool         `json:"hasChartYaml"`
+	Dependencies []Dependency `json:"dependencies"`
+	// Size is the size of the chart files outside its subchart directories
+	Size int64 `json:"size"`
+}
+
+// summary returns the analysis of a chart directory. With a cache, it is keyed
+// by the digest of the chart files outside charts/<subchart>, so only charts
+// whose own files changed are read again.
+func (d *Deduplicator) summary(chartPath string) (*chartSummary, error) {
+	if s, ok := d.summaries[chartPath]; ok {
+		return s, nil
+	}
+
+	var digest string
+	if d.opts.Cache != nil {
+		var err error
+		digest, err = d.opts.Cache.Digest(chartPath, isSubchartDir)
+		if err != nil {
+			return nil, err
+		}
+		digest = cache.Combine(cacheVersion, digest)
+
+		cached := &chartSummary{}
+		if d.opts.Cache.Get(cacheKindChart, digest, cached) {
+			d.summaries[chartPath] = cached
+			return cached, nil
+		}
+	}
+
+	s, err := summarize(chartPath)
+	if err != nil {
+		return nil, err
+	}
+	// The size is only used through the cache; treeSize measures directly otherwise
+	if d.opts.Cache != nil {
+		if s.Size, err = ownSize(chartPath); err != nil {
+			return nil, err
+		}
+		if err := d.opts.Cache.Put(cacheKindChart, digest, s); err != nil {
+			d.opts.Logger.Warn("failed to cache chart analysis", "chart", chartPath, "error", err)
+		}
+	}
+	d.summaries[chartPath] = s
+	return s, nil
+}
+
+// summarize reads the dependencies of a chart directory
+func summarize(chartPath string) (*chartSummary, error) {
+	s := &chartSummary{}
+
+	chartYamlPath := filepath.Join(chartPath, "Chart.yaml")
+	if _, err := os.Stat(chartYamlPath); err == nil {
+		chartYaml, err := readChartYaml(chartYamlPath)
+		if err != nil {
+			return nil, err
+		}
+		s.HasChartYaml = true
+		for _, dep := range chartYaml.Dependencies {
+			// The name selects the copy's directory below charts/
+			if err := common.ValidateDependencyName(dep.Name); err != nil {
+				return nil, fmt.Errorf("%s: %w", chartYamlPath, err)
+			}
+			s.Dependencies = append(s.Dependencies, Dependency{Name: dep.Name, Version: dep.Version})
+		}
+	}
+	return s, nil
+}
+
+// ownSize returns the size of the chart files outside its subchart directories
+func ownSize(chartPath string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(chartPath, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() && path != chartPath && isSubchartDir(relSlash(chartPath, path)) {
+			return filepath.SkipDir
+		}
+		if entry.Type().IsRegular() {
+			info, err := entry.Info()
+			if err != nil {
+				return err
+			}
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
+
+// treeSize returns the size of a chart including its subcharts
+func (d *Deduplicator) treeSize(chartPath string) (int64, error) {
+	if d.opts.Cache == nil {
+		return common.DirSize(chartPath)
+	}
+
+	s, err := d.summary(chartPath)
+	if err != nil {
+		return 0, err
+	}
+	size := s.Size
+
+	entries, err := os.ReadDir(filepath.Join(chartPath, "charts"))
+	if err != nil && !os.IsNotExist(err) {
+		return 0, err
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		sub, err := d.treeSize(filepath.Join(chartPath, "charts", entry.Name()))
+		if err != nil {
+			return 0, err
+		}
+		size += sub
+	}
+	return size, nil
+}
+
+// cachedValuesIndex returns the coalesced values of the chart tree. With a
+// cache, they are keyed by the digest of the whole tree and the values files.
+// The digest does not follow symbolic links below the root, so trees
+// containing links are never cached: a change behind a link would go unseen.
+func (d *Deduplicator) cachedValuesIndex() (*valuesIndex, error) {
+	if d.opts.Cache == nil {
+		return loadValuesIndex(d.opts.ChartPath, d.opts.ValueFiles)
+	}
+	linked, err := containsSymlink(d.opts.ChartPath)
+	if err != nil {
+		return nil, err
+	}
+	if linked {
+		d.opts.Logger.Debug("chart tree contains symbolic links, not caching its values", "chart", d.opts.ChartPath)
+		return loadValuesIndex(d.opts.ChartPath, d.opts.ValueFiles)
+	}
+
+	tree, err := d.opts.Cache.Digest(d.opts.ChartPath, nil)
+	if err != nil {
+		return nil, err
+	}
+	parts := []string{cacheVersion, tree}
+	for _, file := range d.opts.ValueFiles {
+		digest, err := d.opts.Cache.FileDigest(file)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, digest)
+	}
+	key := cache.Combine(parts...)
+
+	var values map[string]interface{}
+	if d.opts.Cache.Get(cacheKindValues, key, &values) {
+		return &valuesIndex{root: d.opts.ChartPath, values: values}, nil
+	}
+
+	index, err := loadValuesIndex(d.opts.ChartPath, d.opts.ValueFiles)
+	if err != nil {
+		return nil, err
+	}
+	if err := d.opts.Cache.Put(cacheKindValues, key, index.values); err != nil {
+		d.opts.Logger.Warn("failed to cache chart values", "chart", d.opts.ChartPath, "error", err)
+	}
+	return index, nil
+}
+
+// containsSymlink reports whether the tree below root holds a symbolic link
+func containsSymlink(root string) (bool, error) {
+	found := false
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path != root && d.Type()&fs.ModeSymlink != 0 {
+			found = true
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	return found, err
+}
+
+// isSubchartDir reports whether a slash separated path relative to a chart
+// names a subchart directory, charts/<name>
+func isSubchartDir(rel string) bool {
+	parts := strings.Split(rel, "/")
+	return len(parts) == 2 && parts[0] == "charts"
+}
+
+// relSlash returns the slash separated path of target relative to base
+func relSlash(base, target string) string {
+	rel, err := filepath.Rel(base, target)
+	if err != nil {
+		return target
+	}
+	return filepath.ToSlash(rel)
+}
@@ -2,7 +2,17 @@ package dedup
 
 import (
 	"fmt"
+	"log/slog"
 	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/harness/helm-optimize/pkg/cache"
+	"github.com/harness/helm-optimize/pkg/common"
+	"github.com/harness/helm-optimize/pkg/lint"
+	"github.com/harness/helm-optimize/pkg/workspace"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/registry"
 )
 
 // Options defines the parameters for deduplication
@@ -12,43 +22,167 @@ type Options struct {
 	Package     bool
 	DryRun      bool
 	ShowDeleted bool
-	Verbose     bool
+	// Check fails with common.ErrNotOptimized when duplicates would be removed; implies DryRun
+	Check bool
+	// Logger receives the diagnostics; slog.Default() when nil
+	Logger *slog.Logger
+	// Include limits deletion to subchart paths matching these globs
+	Include []string
+	// Exclude skips subchart paths matching these globs
+	Exclude []string
+	// NeverDelete protects subchart paths matching these globs from deletion
+	NeverDelete []string
+	// Symlinks is the policy for symlinked subcharts: skip, follow or preserve
+	Symlinks string
+	// KeepStrategy selects which copy of a duplicate dependency is kept
+	KeepStrategy string
+	// Prefer lists the path globs used by the explicit keep strategy
+	Prefer []string
+	// ValuesCheck selects how copies receiving different values are handled
+	ValuesCheck string
+	// ValueFiles are applied on top of the root chart values for the values check
+	ValueFiles []string
+	// Push uploads the packaged chart to this OCI repository, e.g. oci://host/repo; implies Package
+	Push string
+	// PlainHTTP talks to the Push registry over HTTP instead of HTTPS
+	PlainHTTP bool
+	// ReportFile receives the JSON report of the run
+	ReportFile string
+	// Cache holds the analysis of unchanged charts across runs; nil disables caching
+	Cache *cache.Cache
+	// Signer writes a provenance file for the packaged chart; implies Package
+	Signer *workspace.Signer
+	// Lint runs Helm's linter before and after deduplication and fails on
+	// newly introduced errors; nil disables linting
+	Lint *lint.Options
+	// VerifyLoad checks that Helm can still load the deduplicated chart
+	// before it is packaged
+	VerifyLoad bool
 }
 
 // Run executes the deduplication process with the provided options
 func Run(opts Options) error {
 	// Validate chart path
 	if _, err := os.Stat(opts.ChartPath); os.IsNotExist(err) {
-		return fmt.Errorf("chart path '%s' does not exist", opts.ChartPath)
+		return fmt.Errorf("%w: chart path '%s' does not exist", common.ErrChartNotFound, opts.ChartPath)
+	}
+	if _, err := os.Stat(filepath.Join(opts.ChartPath, "Chart.yaml")); err != nil {
+		return fmt.Errorf("%w: no Chart.yaml in '%s'", common.ErrChartNotFound, opts.ChartPath)
+	}
+
+	// Check the push target before anything is changed
+	if opts.Push != "" && !strings.HasPrefix(opts.Push, registry.OCIScheme+"://") {
+		return fmt.Errorf("push target '%s' must start with %s://", opts.Push, registry.OCIScheme)
+	}
+
+	if opts.Check {
+		opts.DryRun = true
 	}
 
 	// Set default output directory if not specified
 	if opts.OutputDir == "" {
-		opts.OutputDir = opts.ChartPath
+		opts.OutputDir = filepath.Dir(filepath.Clean(opts.ChartPath))
+	}
+
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
 	}
-	
-	fmt.Printf("Starting deduplication for chart at '%s'...\n", opts.ChartPath)
-	
+	opts.Logger.Info("starting deduplication", "chart", opts.ChartPath)
+
 	// Create the deduplicator
 	deduplicator := NewDeduplicator(opts)
-	
+
+	// Lint before changing anything, so that only new findings are reported
+	var guard *lint.Guard
+	if opts.Lint != nil && !opts.DryRun {
+		var err error
+		if guard, err = lint.Begin(opts.ChartPath, *opts.Lint); err != nil {
+			return err
+		}
+	}
+
 	// Run the deduplication algorithm
 	deletedPaths, err := deduplicator.DeduplicateChart(opts.ChartPath)
 	if err != nil {
-		return fmt.Errorf("deduplication failed: %v", err)
+		return fmt.Errorf("deduplication failed: %w", err)
 	}
-	
+
+	if opts.Check {
+		var changes []common.Change
+		for _, path := range deletedPaths {
+			changes = append(changes, common.Change{Action: common.ActionDelete, Path: path})
+		}
+		return common.CheckChanges(os.Stdout, "dedup", opts.ChartPath, changes)
+	}
+
 	// Report results
-	fmt.Printf("Deduplication completed. %d duplicate dependencies removed.\n", len(deletedPaths))
-	
+	opts.Logger.Info("deduplication completed", "chart", opts.ChartPath, "removed", len(deletedPaths))
+	report := deduplicator.Report().Relative(opts.ChartPath)
+
+	// An optimized chart introducing lint errors is never packaged
+	if guard != nil {
+		delta, err := guard.End()
+		if delta != nil {
+			delta.Print(os.Stdout)
+			report.Lint = delta
+		}
+		if err != nil {
+			// Keep the findings in the report of the failed run
+			if opts.ReportFile != "" {
+				if werr := report.WriteFile(opts.ReportFile); werr != nil {
+					return fmt.Errorf("failed to write report: %w", werr)
+				}
+			}
+			return err
+		}
+	}
+
+	if opts.VerifyLoad && !opts.DryRun {
+		if _, err := loader.Load(opts.ChartPath); err != nil {
+			return fmt.Errorf("%w: deduplicated chart cannot be loaded: %w", common.ErrVerificationFailed, err)
+		}
+		opts.Logger.Info("verification passed, deduplicated chart loads successfully", "chart", opts.ChartPath)
+	}
+
 	// Package chart if requested
-	if opts.Package && !opts.DryRun {
-		fmt.Println("Packaging deduplicated chart...")
-		if err := packageChart(opts.ChartPath); err != nil {
-			return fmt.Errorf("failed to package chart: %v", err)
+	if (opts.Package || opts.Push != "" || opts.Signer != nil) && !opts.DryRun {
+		opts.Logger.Info("packaging deduplicated chart", "chart", opts.ChartPath)
+		archive, err := packageChart(opts.ChartPath, opts.OutputDir, opts.Logger)
+		if err != nil {
+			return fmt.Errorf("failed to package chart: %w", err)
+		}
+		report.Package = &PackageResult{Archive: archive}
+
+		if opts.Signer != nil {
+			prov, err := opts.Signer.Sign(archive)
+			if err != nil {
+				return err
+			}
+			report.Package.Provenance = prov
+			opts.Logger.Info("provenance written", "path", prov)
+		}
+
+		if opts.Push != "" {
+			opts.Logger.Info("pushing chart", "archive", archive, "remote", opts.Push)
+			pushed, err := workspace.Push(archive, workspace.PushOptions{
+				Remote:     opts.Push,
+				PlainHTTP:  opts.PlainHTTP,
+				Provenance: report.Package.Provenance,
+			})
+			if err != nil {
+				return err
+			}
+			report.Package.Pushed = pushed
+			opts.Logger.Info("pushed chart", "ref", pushed.Ref, "digest", pushed.Digest)
+		}
+	}
+
+	if opts.ReportFile != "" {
+		if err := report.WriteFile(opts.ReportFile); err != nil {
+			return fmt.Errorf("failed to write report: %w", err)
 		}
 	}
-	
+
 	return nil
 }
 
@@ -57,12 +191,18 @@ type ChartYaml struct {
 	Dependencies []struct {
 		Name    string `yaml:"name"`
 		Version string `yaml:"version"`
+		// Alias is the values key of the dependency, when it differs from the name
+		Alias string `yaml:"alias"`
 		// Other fields omitted for simplicity
 	} `yaml:"dependencies"`
 }
 
-// packageChart packages a chart
-func packageChart(chartPath string) error {
-	fmt.Println("Packaging functionality not implemented yet.")
-	return nil
+// packageChart packages a chart into the output directory and returns the archive path
+func packageChart(chartPath, outputDir string, logger *slog.Logger) (string, error) {
+	archive, err := workspace.Package(chartPath, outputDir)
+	if err != nil {
+		return "", err
+	}
+	logger.Info("packaged chart", "archive", archive)
+	return archive, nil
 }
@@ -2,9 +2,14 @@ package dedup
 
 import (
 	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
+
+	"github.com/harness/helm-optimize/pkg/common"
 )
 
 // Dependency represents a chart dependency with name and version
@@ -20,49 +25,120 @@ func (d Dependency) Key() string {
 
 // ChartPath represents a chart location in the filesystem
 type ChartPath struct {
+	// Dependency is the name and version declared by the consumer
+	Dependency Dependency
 	Path       string
 	ParentPath string
+	// ConsumerPath is the chart whose Chart.yaml declares the dependency
+	ConsumerPath string
+	// Order is the position in which the copy was encountered during traversal
+	Order int
 }
 
 // Deduplicator manages the dependency deduplication process
 type Deduplicator struct {
 	// Maps dependency name-version to the chart paths where it was found
 	overallDependencies map[string][]ChartPath
+	// Dependency keys in the order they were first encountered
+	dependencyOrder []string
+	// Number of dependency copies found so far
+	copies int
 	// List of paths to dependencies that will be kept
 	currentDependencies []string
 	// List of paths to dependencies that will be deleted
 	deleteDependencies []string
+	// Duplicate dependencies kept because they are protected
+	protectedDependencies []common.ProtectedPath
+	// Report describes every duplicate group and the decision taken
+	report Report
+	// Coalesced values of the chart tree, loaded on first use by checkValues
+	values *valuesIndex
+	// Analysis of every chart directory visited, keyed by path
+	summaries map[string]*chartSummary
 	// Mutex for thread safety
-	mu          sync.Mutex
-	opts        Options
+	mu   sync.Mutex
+	opts Options
+	// Selector decides which subcharts may be traversed and deleted
+	selector common.Selector
+	// Traversal detects symlink cycles in the chart tree
+	traversal *common.Traversal
 }
 
 // NewDeduplicator creates a new Deduplicator
 func NewDeduplicator(opts Options) *Deduplicator {
+	if opts.KeepStrategy == "" {
+		opts.KeepStrategy = StrategyFirst
+	}
+	if opts.ValuesCheck == "" {
+		opts.ValuesCheck = ValuesCheckWarn
+	}
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
 	return &Deduplicator{
 		overallDependencies: make(map[string][]ChartPath),
 		currentDependencies: []string{},
 		deleteDependencies:  []string{},
+		summaries:           map[string]*chartSummary{},
 		opts:                opts,
+		report:              Report{ChartPath: opts.ChartPath, Strategy: opts.KeepStrategy},
+		selector: common.Selector{
+			Root:        opts.ChartPath,
+			Include:     opts.Include,
+			Exclude:     opts.Exclude,
+			NeverDelete: opts.NeverDelete,
+			Symlinks:    opts.Symlinks,
+		},
+		traversal: common.NewTraversal(),
+	}
+}
+
+// Analyze finds the duplicate dependencies of a chart and decides which copies
+// would be kept, without changing anything on disk
+func (d *Deduplicator) Analyze(chartPath string) (Report, error) {
+	if !isKnownStrategy(d.opts.KeepStrategy) {
+		return Report{}, fmt.Errorf("unknown keep strategy '%s' (known: %s)", d.opts.KeepStrategy, strings.Join(KnownStrategies, ", "))
+	}
+	if d.opts.KeepStrategy == StrategyExplicit && len(d.opts.Prefer) == 0 {
+		return Report{}, fmt.Errorf("the %q keep strategy requires at least one --prefer pattern", StrategyExplicit)
+	}
+	if !contains(KnownValuesChecks, d.opts.ValuesCheck) {
+		return Report{}, fmt.Errorf("unknown values check '%s' (known: %s)", d.opts.ValuesCheck, strings.Join(KnownValuesChecks, ", "))
+	}
+	if err := common.ValidateSymlinkPolicy(d.opts.Symlinks); err != nil {
+		return Report{}, err
+	}
+
+	// Collect every dependency copy in the chart tree
+	if err := d.processDependencies(chartPath); err != nil {
+		return Report{}, err
+	}
+
+	// Decide which copy of each duplicate group is kept
+	if err := d.resolveDuplicates(); err != nil {
+		return Report{}, err
 	}
+
+	return d.report, nil
 }
 
 // DeduplicateChart performs dependency deduplication on a chart
 func (d *Deduplicator) DeduplicateChart(chartPath string) ([]string, error) {
-	// Start the deduplication process from the root chart path
-	err := d.processDependencies(chartPath)
-	if err != nil {
+	if _, err := d.Analyze(chartPath); err != nil {
 		return nil, err
 	}
-	
+
+	d.report.Print()
+
 	// Delete duplicate dependencies
 	if !d.opts.DryRun {
 		for _, path := range d.deleteDependencies {
-			if d.opts.Verbose || d.opts.ShowDeleted {
+			d.opts.Logger.Debug("removing duplicate dependency", "path", path)
+			if d.opts.ShowDeleted {
 				fmt.Printf("Removing duplicate dependency: %s\n", path)
 			}
-			if err := os.RemoveAll(path); err != nil {
-				return nil, fmt.Errorf("failed to remove %s: %v", path, err)
+			if err := common.RemoveAll(d.opts.ChartPath, path); err != nil {
+				return nil, fmt.Errorf("failed to remove %s: %w", path, err)
 			}
 		}
 	} else if d.opts.ShowDeleted {
@@ -71,107 +147,108 @@ func (d *Deduplicator) DeduplicateChart(chartPath string) ([]string, error) {
 			fmt.Printf("  %s\n", path)
 		}
 	}
-	
+
 	return d.deleteDependencies, nil
 }
 
-// processDependencies processes dependencies for the chart at the given path
+// Dependencies walks the chart tree the way deduplication does and returns
+// every unpacked dependency copy keyed by name-version, without deciding
+// which copies are duplicates
+func (d *Deduplicator) Dependencies(chartPath string) (map[string][]ChartPath, error) {
+	if err := d.processDependencies(chartPath); err != nil {
+		return nil, err
+	}
+	return d.overallDependencies, nil
+}
+
+// ProtectedDependencies returns the duplicate dependencies kept because they are protected
+func (d *Deduplicator) ProtectedDependencies() []common.ProtectedPath {
+	return d.protectedDependencies
+}
+
+// Report returns the deduplication report
+func (d *Deduplicator) Report() Report {
+	return d.report
+}
+
+// processDependencies records the dependencies of the chart at the given path
+// and recurses into its charts directory
 func (d *Deduplicator) processDependencies(chartPath string) error {
-	// Look for Chart.yaml file
-	chartYamlPath := filepath.Join(chartPath, "Chart.yaml")
-	
-	// Check if Chart.yaml exists
-	if _, err := os.Stat(chartYamlPath); err == nil {
-		// Read and parse Chart.yaml
-		chartYaml, err := readChartYaml(chartYamlPath)
-		if err != nil {
-			return err
-		}
-		
-		if d.opts.Verbose {
-			fmt.Printf("Processing dependencies in %s\n", chartYamlPath)
-		}
-		
+	// A symlinked subchart linking back to one of its parents closes a cycle
+	entered, err := d.traversal.Enter(chartPath)
+	if err != nil {
+		return err
+	}
+	if !entered {
+		d.opts.Logger.Warn("symlink cycle detected, skipping", "chart", chartPath)
+		return nil
+	}
+	defer d.traversal.Leave(chartPath)
+
+	// Read the dependencies declared in Chart.yaml
+	summary, err := d.summary(chartPath)
+	if err != nil {
+		return err
+	}
+
+	if summary.HasChartYaml {
+		d.opts.Logger.Debug("processing dependencies", "chart", chartPath)
+
+		// Get parent directory path to check context
+		parentPath := filepath.Dir(chartPath)
+
 		// Process dependencies in Chart.yaml
-		for _, dep := range chartYaml.Dependencies {
-			dependency := Dependency{
-				Name:    dep.Name,
-				Version: dep.Version,
-			}
-			
+		for _, dependency := range summary.Dependencies {
 			depKey := dependency.Key()
 			depPath := filepath.Join(chartPath, "charts", dependency.Name)
-			
+
+			// Only unpacked copies can be deduplicated; a missing directory
+			// usually means the copy was already removed by a previous run
+			if info, err := os.Stat(depPath); err != nil || !info.IsDir() {
+				d.opts.Logger.Debug("dependency not unpacked, skipping", "dependency", depKey, "path", depPath)
+				continue
+			}
+
+			// Excluded subcharts take no part in deduplication
+			if d.selector.Excluded(depPath) {
+				d.opts.Logger.Debug("skipping excluded dependency", "dependency", depKey, "path", depPath)
+				continue
+			}
+
 			d.mu.Lock()
-			// Get parent directory path to check context
-			parentPath := filepath.Dir(chartPath)
-			
-			// Check if dependency already exists in overall dependencies
-			if paths, found := d.overallDependencies[depKey]; found {
-				// Check if this is a true duplicate or a specialized version
-				isTrueDuplicate := false
-				var originalPath string
-				
-				// Basic rule: charts at the same level of hierarchy with the same parent are considered duplicates
-				// Charts in different parts of hierarchy should be preserved
-				for _, existingChartPath := range paths {
-					// Store original path for reference
-					if originalPath == "" {
-						originalPath = existingChartPath.Path
-					}
-					
-					// If exact same path, skip it entirely - this is not a duplicate but the same dependency
-					if existingChartPath.Path == depPath {
-						// This is the exact same path, not a duplicate
-						isTrueDuplicate = false
-						break
-					}
-					
-					// Check for duplicate at same parent level
-					if existingChartPath.ParentPath == parentPath {
-						isTrueDuplicate = true
-					}
+			paths, found := d.overallDependencies[depKey]
+			if !found {
+				d.dependencyOrder = append(d.dependencyOrder, depKey)
+			}
+
+			// The same path listed twice is the same dependency, not a duplicate
+			alreadySeen := false
+			for _, existing := range paths {
+				if existing.Path == depPath {
+					alreadySeen = true
+					break
 				}
-				
-				if isTrueDuplicate {
-					// Duplicate found - but double check that we're not deleting the original path
-					if depPath != originalPath {
-						d.deleteDependencies = append(d.deleteDependencies, depPath)
-						if d.opts.Verbose {
-							fmt.Printf("  Found duplicate dependency %s at %s (original at %s)\n", 
-								depKey, depPath, originalPath)
-						}
-					} else {
-						// This is the original path, we shouldn't delete it
-						newChartPath := ChartPath{Path: depPath, ParentPath: parentPath}
-						d.overallDependencies[depKey] = append(paths, newChartPath)
-						d.currentDependencies = append(d.currentDependencies, depPath)
-						if d.opts.Verbose {
-							fmt.Printf("  Found original dependency %s at %s (keeping)\n", depKey, depPath)
-						}
-					}
+			}
+
+			if !alreadySeen {
+				d.overallDependencies[depKey] = append(paths, ChartPath{
+					Dependency:   dependency,
+					Path:         depPath,
+					ParentPath:   parentPath,
+					ConsumerPath: chartPath,
+					Order:        d.copies,
+				})
+				d.copies++
+				if found {
+					d.opts.Logger.Debug("found another copy of dependency", "dependency", depKey, "path", depPath)
 				} else {
-					// Similar dependency in different context - keep it
-					newChartPath := ChartPath{Path: depPath, ParentPath: parentPath}
-					d.overallDependencies[depKey] = append(paths, newChartPath)
-					d.currentDependencies = append(d.currentDependencies, depPath)
-					if d.opts.Verbose {
-						fmt.Printf("  Found contextual dependency %s at %s (keeping)\n", depKey, depPath)
-					}
-				}
-			} else {
-				// New dependency - add to overall dependencies and current dependencies
-				newChartPath := ChartPath{Path: depPath, ParentPath: parentPath}
-				d.overallDependencies[depKey] = []ChartPath{newChartPath}
-				d.currentDependencies = append(d.currentDependencies, depPath)
-				if d.opts.Verbose {
-					fmt.Printf("  Found new dependency %s at %s\n", depKey, depPath)
+					d.opts.Logger.Debug("found new dependency", "dependency", depKey, "path", depPath)
 				}
 			}
 			d.mu.Unlock()
 		}
 	}
-	
+
 	// Check for charts directory
 	chartsDir := filepath.Join(chartPath, "charts")
 	if _, err := os.Stat(chartsDir); err == nil {
@@ -180,30 +257,157 @@ func (d *Deduplicator) processDependencies(chartPath string) error {
 		if err != nil {
 			return err
 		}
-		
+
 		// For each entry in the charts directory
 		for _, entry := range entries {
-			if entry.IsDir() {
+			if common.IsDirOrLink(chartsDir, entry, d.opts.Symlinks) {
 				subChartPath := filepath.Join(chartsDir, entry.Name())
-				
-				// Skip directories that are marked for deletion
-				skipDir := false
-				for _, deletePath := range d.deleteDependencies {
-					if deletePath == subChartPath {
-						skipDir = true
-						break
-					}
+
+				// Skip excluded directories
+				if d.selector.Excluded(subChartPath) {
+					continue
 				}
-				
-				if !skipDir {
-					// Recursively process the subchart
-					if err := d.processDependencies(subChartPath); err != nil {
-						return err
-					}
+
+				// Recursively process the subchart
+				if err := d.processDependencies(subChartPath); err != nil {
+					return err
 				}
 			}
 		}
 	}
-	
+
 	return nil
 }
+
+// resolveDuplicates groups the copies of each dependency and selects the copy to keep.
+// Charts at the same level of hierarchy with the same parent are considered duplicates;
+// copies in different parts of the hierarchy are preserved. The strategies
+// choosing by position, shallowest and explicit, compare every copy of a
+// dependency across the whole tree instead.
+func (d *Deduplicator) resolveDuplicates() error {
+	type copyGroup struct {
+		key    string
+		copies []ChartPath
+	}
+
+	acrossTree := d.opts.KeepStrategy == StrategyShallowest || d.opts.KeepStrategy == StrategyExplicit
+
+	var groups []copyGroup
+	for _, depKey := range d.dependencyOrder {
+		byParent := map[string][]ChartPath{}
+		var parents []string
+		for _, cp := range d.overallDependencies[depKey] {
+			parent := cp.ParentPath
+			if acrossTree {
+				parent = ""
+			}
+			if _, ok := byParent[parent]; !ok {
+				parents = append(parents, parent)
+			}
+			byParent[parent] = append(byParent[parent], cp)
+		}
+		for _, parent := range parents {
+			groups = append(groups, copyGroup{key: depKey, copies: byParent[parent]})
+		}
+	}
+
+	// Resolve shallow groups first so copies inside deleted subtrees are ignored
+	sort.SliceStable(groups, func(i, j int) bool {
+		return minDepth(groups[i].copies) < minDepth(groups[j].copies)
+	})
+
+	for _, group := range groups {
+		var candidates []ChartPath
+		for _, cp := range group.copies {
+			if !d.isDeleted(cp.Path) {
+				candidates = append(candidates, cp)
+			}
+		}
+		if len(candidates) == 0 {
+			continue
+		}
+		if len(candidates) == 1 {
+			d.currentDependencies = append(d.currentDependencies, candidates[0].Path)
+			continue
+		}
+
+		kept, reason, err := d.chooseKeeper(candidates)
+		if err != nil {
+			return err
+		}
+
+		depKey := group.key
+		entry := DuplicateGroup{Dependency: depKey, Kept: kept.Path, Reason: reason}
+		d.currentDependencies = append(d.currentDependencies, kept.Path)
+
+		for _, cp := range candidates {
+			if cp.Path == kept.Path {
+				continue
+			}
+			if protection := d.selector.Protection(cp.Path); protection != "" {
+				protected := common.ProtectedPath{Path: cp.Path, Reason: protection}
+				d.protectedDependencies = append(d.protectedDependencies, protected)
+				d.currentDependencies = append(d.currentDependencies, cp.Path)
+				entry.Protected = append(entry.Protected, protected)
+				d.opts.Logger.Debug("duplicate dependency is protected, keeping", "dependency", depKey, "path", cp.Path, "reason", protection)
+				continue
+			}
+
+			// Removing the copy must not change the values its consumer sees
+			conflict, err := d.checkValues(kept.Path, cp.Path)
+			if err != nil {
+				return err
+			}
+			if conflict != nil {
+				entry.ValueConflicts = append(entry.ValueConflicts, *conflict)
+				if !conflict.Removed {
+					d.currentDependencies = append(d.currentDependencies, cp.Path)
+					d.opts.Logger.Debug("duplicate dependency receives different values, keeping", "dependency", depKey, "path", cp.Path, "keys", conflict.Keys)
+					continue
+				}
+				d.opts.Logger.Warn("duplicate dependency receives different values, removing anyway", "dependency", depKey, "path", cp.Path, "keys", conflict.Keys)
+			}
+
+			// Never plan a deletion that would leave the root chart
+			if err := common.Confine(d.opts.ChartPath, cp.Path); err != nil {
+				return err
+			}
+
+			d.deleteDependencies = append(d.deleteDependencies, cp.Path)
+			entry.Removed = append(entry.Removed, cp.Path)
+			d.opts.Logger.Debug("found duplicate dependency", "dependency", depKey, "path", cp.Path, "kept", kept.Path)
+		}
+
+		d.report.Groups = append(d.report.Groups, entry)
+	}
+
+	d.report.Deleted = d.deleteDependencies
+	d.report.Protected = d.protectedDependencies
+	return nil
+}
+
+// isDeleted reports whether the path is, or lies below, a path marked for deletion
+func (d *Deduplicator) isDeleted(path string) bool {
+	for _, deletePath := range d.deleteDependencies {
+		if path == deletePath || strings.HasPrefix(path, deletePath+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// minDepth returns the depth of the shallowest copy
+func minDepth(copies []ChartPath) int {
+	shallowest := depth(copies[0].Path)
+	for _, cp := range copies[1:] {
+		if n := depth(cp.Path); n < shallowest {
+			shallowest = n
+		}
+	}
+	return shallowest
+}
+
+// depth returns the number of path elements
+func depth(path string) int {
+	return len(strings.Split(filepath.Clean(path), string(filepath.Separator)))
+}
@@ -0,0 +1,130 @@
+package dedup
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/harness/helm-optimize/pkg/common"
+	"github.com/harness/helm-optimize/pkg/lint"
+	"github.com/harness/helm-optimize/pkg/workspace"
+)
+
+// Report describes the outcome of a deduplication run
+type Report struct {
+	ChartPath string                 `json:"chartPath"`
+	Strategy  string                 `json:"strategy"`
+	Groups    []DuplicateGroup       `json:"groups"`
+	Deleted   []string               `json:"deleted"`
+	Protected []common.ProtectedPath `json:"protected,omitempty"`
+	// Package describes the packaged chart, when the chart was packaged
+	Package *PackageResult `json:"package,omitempty"`
+	// Lint lists the lint findings introduced by deduplication, when linted
+	Lint *lint.Delta `json:"lint,omitempty"`
+}
+
+// PackageResult describes the archive written after deduplication
+type PackageResult struct {
+	Archive string `json:"archive"`
+	// Provenance is the provenance file written when the chart is signed
+	Provenance string `json:"provenance,omitempty"`
+	// Pushed describes the upload to an OCI registry, when requested
+	Pushed *workspace.PushResult `json:"pushed,omitempty"`
+}
+
+// DuplicateGroup describes a set of identical dependency copies sharing a parent
+type DuplicateGroup struct {
+	// Dependency is the name-version key of the duplicated dependency
+	Dependency string `json:"dependency"`
+	// Kept is the copy that was kept
+	Kept string `json:"kept"`
+	// Reason explains why Kept was chosen
+	Reason string `json:"reason"`
+	// Removed lists the copies that are deleted
+	Removed []string `json:"removed,omitempty"`
+	// Protected lists the copies kept because they are protected
+	Protected []common.ProtectedPath `json:"protected,omitempty"`
+	// ValueConflicts lists the copies whose effective values differ from the kept copy
+	ValueConflicts []ValuesConflict `json:"valueConflicts,omitempty"`
+}
+
+// Print writes a human readable summary of the duplicate groups
+func (r Report) Print() {
+	for _, group := range r.Groups {
+		fmt.Printf("Duplicate dependency %s:\n", group.Dependency)
+		fmt.Printf("  kept      %s (%s)\n", group.Kept, group.Reason)
+		for _, path := range group.Removed {
+			fmt.Printf("  removed   %s\n", path)
+		}
+		for _, p := range group.Protected {
+			fmt.Printf("  protected %s (%s)\n", p.Path, p.Reason)
+		}
+		for _, c := range group.ValueConflicts {
+			if c.Removed {
+				fmt.Printf("  WARNING   %s receives different values (%s) and is removed\n", c.Path, strings.Join(c.Keys, ", "))
+			} else {
+				fmt.Printf("  kept      %s (receives different values: %s)\n", c.Path, strings.Join(c.Keys, ", "))
+			}
+		}
+	}
+}
+
+// WriteFile writes the report as indented JSON
+func (r Report) WriteFile(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// Relative returns a copy of the report with all paths relative to root
+func (r Report) Relative(root string) Report {
+	rel := func(path string) string {
+		if p, err := filepath.Rel(root, path); err == nil {
+			return filepath.ToSlash(p)
+		}
+		return path
+	}
+	relProtected := func(list []common.ProtectedPath) []common.ProtectedPath {
+		var out []common.ProtectedPath
+		for _, p := range list {
+			out = append(out, common.ProtectedPath{Path: rel(p.Path), Reason: p.Reason})
+		}
+		return out
+	}
+	relPaths := func(list []string) []string {
+		var out []string
+		for _, p := range list {
+			out = append(out, rel(p))
+		}
+		return out
+	}
+
+	out := Report{
+		ChartPath: r.ChartPath,
+		Strategy:  r.Strategy,
+		Deleted:   relPaths(r.Deleted),
+		Protected: relProtected(r.Protected),
+		Package:   r.Package,
+	}
+	for _, group := range r.Groups {
+		out.Groups = append(out.Groups, DuplicateGroup{
+			Dependency: group.Dependency,
+			Kept:       rel(group.Kept),
+			Reason:     group.Reason,
+			Removed:    relPaths(group.Removed),
+			Protected:  relProtected(group.Protected),
+			ValueConflicts: func() []ValuesConflict {
+				var out []ValuesConflict
+				for _, c := range group.ValueConflicts {
+					out = append(out, ValuesConflict{Path: rel(c.Path), Keys: c.Keys, Removed: c.Removed})
+				}
+				return out
+			}(),
+		})
+	}
+	return out
+}
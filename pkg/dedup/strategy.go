@@ -0,0 +1,107 @@
+package dedup
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/harness/helm-optimize/pkg/common"
+)
+
+// Strategies for choosing which copy of a duplicate dependency is kept
+const (
+	// StrategyFirst keeps the first copy encountered during traversal
+	StrategyFirst = "first"
+	// StrategyShallowest keeps the copy closest to the umbrella root
+	StrategyShallowest = "shallowest"
+	// StrategyLargestConsumer keeps the copy whose consuming chart is the largest
+	StrategyLargestConsumer = "largest-consumer"
+	// StrategyExplicit keeps the copy matching one of the Prefer patterns
+	StrategyExplicit = "explicit"
+)
+
+// KnownStrategies lists the supported keep strategies
+var KnownStrategies = []string{
+	StrategyFirst,
+	StrategyShallowest,
+	StrategyLargestConsumer,
+	StrategyExplicit,
+}
+
+// isKnownStrategy reports whether the name is a supported keep strategy
+func isKnownStrategy(name string) bool {
+	return contains(KnownStrategies, name)
+}
+
+// contains reports whether the list holds the value
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// chooseKeeper selects the copy to keep from a group of duplicates according
+// to the configured strategy and returns the reason for the choice.
+// Candidates are in traversal order, so ties always go to the first copy.
+func (d *Deduplicator) chooseKeeper(candidates []ChartPath) (ChartPath, string, error) {
+	first := candidates[0]
+	for _, cp := range candidates {
+		if cp.Order < first.Order {
+			first = cp
+		}
+	}
+
+	switch d.opts.KeepStrategy {
+	case StrategyShallowest:
+		kept := first
+		for _, cp := range candidates {
+			if depth(cp.Path) < depth(kept.Path) {
+				kept = cp
+			}
+		}
+		return kept, fmt.Sprintf("shallowest copy, %d levels below the root chart", d.levels(kept.Path)), nil
+
+	case StrategyLargestConsumer:
+		kept := first
+		var keptSize int64 = -1
+		for _, cp := range candidates {
+			size, err := d.treeSize(cp.ConsumerPath)
+			if err != nil {
+				return ChartPath{}, "", fmt.Errorf("failed to measure %s: %w", cp.ConsumerPath, err)
+			}
+			if size > keptSize {
+				kept, keptSize = cp, size
+			}
+		}
+		return kept, fmt.Sprintf("consumed by the largest parent chart %s (%d bytes)", kept.ConsumerPath, keptSize), nil
+
+	case StrategyExplicit:
+		for _, pattern := range d.opts.Prefer {
+			for _, cp := range candidates {
+				if common.MatchGlob(pattern, d.selector.Rel(cp.Path)) {
+					return cp, fmt.Sprintf("matched preferred pattern %q", pattern), nil
+				}
+			}
+		}
+		return first, "no preferred pattern matched, kept the first copy encountered", nil
+
+	default:
+		return first, "first copy encountered during traversal", nil
+	}
+}
+
+// levels returns how many charts directories separate the path from the root chart
+func (d *Deduplicator) levels(path string) int {
+	count := 0
+	for dir := path; dir != filepath.Dir(dir); dir = filepath.Dir(dir) {
+		if filepath.Base(dir) == "charts" {
+			count++
+		}
+		if filepath.Clean(dir) == filepath.Clean(d.opts.ChartPath) {
+			break
+		}
+	}
+	return count
+}
@@ -1,7 +1,10 @@
 package dedup
 
 import (
+	"fmt"
 	"os"
+
+	"github.com/harness/helm-optimize/pkg/common"
 	"gopkg.in/yaml.v3"
 )
 
@@ -11,11 +14,11 @@ func readChartYaml(path string) (*ChartYaml, error) {
 	if err != nil {
 		return nil, err
 	}
-	
+
 	var chartYaml ChartYaml
 	if err := yaml.Unmarshal(data, &chartYaml); err != nil {
-		return nil, err
+		return nil, fmt.Errorf("%w: failed to parse %s: %w", common.ErrInvalidChart, path, err)
 	}
-	
+
 	return &chartYaml, nil
 }
@@ -0,0 +1,229 @@
+package dedup
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/harness/helm-optimize/pkg/common"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+)
+
+// Values check modes for copies whose effective values differ
+const (
+	// ValuesCheckOff skips the values comparison
+	ValuesCheckOff = "off"
+	// ValuesCheckWarn deletes the copy but reports the divergent values
+	ValuesCheckWarn = "warn"
+	// ValuesCheckRefuse keeps copies whose values differ from the surviving copy
+	ValuesCheckRefuse = "refuse"
+)
+
+// KnownValuesChecks lists the supported values check modes
+var KnownValuesChecks = []string{ValuesCheckOff, ValuesCheckWarn, ValuesCheckRefuse}
+
+// maxReportedKeys limits how many divergent keys are listed per copy
+const maxReportedKeys = 5
+
+// ValuesConflict describes a duplicate copy configured differently from the kept copy
+type ValuesConflict struct {
+	Path string `json:"path"`
+	// Keys lists the dotted value paths that differ
+	Keys []string `json:"keys"`
+	// Removed is false when the copy was kept because of the conflict
+	Removed bool `json:"removed"`
+}
+
+// valuesIndex holds the coalesced values of the whole chart tree
+type valuesIndex struct {
+	root   string
+	values map[string]interface{}
+}
+
+// loadValuesIndex coalesces the values of every chart in the tree, with the
+// supplied values files applied on top of the root chart values
+func loadValuesIndex(chartPath string, valueFiles []string) (*valuesIndex, error) {
+	c, err := loader.Load(chartPath)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to load chart for values check: %w", common.ErrInvalidChart, err)
+	}
+
+	overrides, err := common.LoadValueFiles(valueFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	// Aliased subcharts receive their defaults under the alias, and disabled
+	// ones are dropped, only once the dependencies are processed
+	if err := chartutil.ProcessDependenciesWithMerge(c, overrides); err != nil {
+		return nil, fmt.Errorf("failed to process dependencies: %w", err)
+	}
+	values, err := chartutil.CoalesceValues(c, overrides)
+	if err != nil {
+		return nil, fmt.Errorf("failed to coalesce values: %w", err)
+	}
+
+	return &valuesIndex{root: chartPath, values: values.AsMap()}, nil
+}
+
+// effectiveValues returns the values every instance of the subchart at path
+// receives. Helm nests subchart values under the alias or name each parent
+// declares the subchart with, so a subchart declared under several aliases
+// has several instances, and copies the parent's globals into every subchart.
+func (v *valuesIndex) effectiveValues(path string) []map[string]interface{} {
+	rel, err := filepath.Rel(v.root, path)
+	if err != nil {
+		return nil
+	}
+
+	instances := []map[string]interface{}{v.values}
+	parent, dir := v.root, v.root
+	for _, element := range strings.Split(rel, string(filepath.Separator)) {
+		dir = filepath.Join(dir, element)
+		if element == "charts" {
+			continue
+		}
+
+		var next []map[string]interface{}
+		for _, values := range instances {
+			for _, key := range valuesKeys(parent, dir, element) {
+				sub, ok := values[key].(map[string]interface{})
+				if !ok {
+					sub = map[string]interface{}{}
+				}
+				next = append(next, sub)
+			}
+		}
+		instances = next
+		parent = dir
+	}
+
+	return instances
+}
+
+// valuesKeys returns the keys the values of the subchart in dir live under in
+// the values of its parent: the alias of every dependency declaring it, or
+// its chart name
+func valuesKeys(parent, dir, element string) []string {
+	name := element
+	if meta := common.ReadChartMetadata(dir); meta != nil && meta.Name != "" {
+		name = meta.Name
+	}
+
+	var keys []string
+	seen := map[string]bool{}
+	if chartYaml, err := readChartYaml(filepath.Join(parent, "Chart.yaml")); err == nil {
+		for _, dep := range chartYaml.Dependencies {
+			if dep.Name != name {
+				continue
+			}
+			key := name
+			if dep.Alias != "" {
+				key = dep.Alias
+			}
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	if len(keys) == 0 {
+		keys = []string{name}
+	}
+	return keys
+}
+
+// divergentInstances returns the value paths for which an instance of the
+// candidate receives other values than the kept copy. Each candidate instance
+// is compared with the closest instance of the kept copy.
+func divergentInstances(kept, candidate []map[string]interface{}) []string {
+	var keys []string
+	seen := map[string]bool{}
+	for _, c := range candidate {
+		var closest []string
+		for i, k := range kept {
+			if diff := divergentKeys(k, c); i == 0 || len(diff) < len(closest) {
+				closest = diff
+			}
+		}
+		for _, key := range closest {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+
+	sort.Strings(keys)
+	return keys
+}
+
+// divergentKeys returns the dotted value paths that differ between two value trees
+func divergentKeys(a, b map[string]interface{}) []string {
+	flatA, flatB := map[string]interface{}{}, map[string]interface{}{}
+	flatten("", a, flatA)
+	flatten("", b, flatB)
+
+	var keys []string
+	for key, value := range flatA {
+		if other, ok := flatB[key]; !ok || !reflect.DeepEqual(value, other) {
+			keys = append(keys, key)
+		}
+	}
+	for key := range flatB {
+		if _, ok := flatA[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+	return keys
+}
+
+// flatten converts nested maps into dotted keys
+func flatten(prefix string, in map[string]interface{}, out map[string]interface{}) {
+	for key, value := range in {
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+		if nested, ok := value.(map[string]interface{}); ok && len(nested) > 0 {
+			flatten(path, nested, out)
+			continue
+		}
+		out[path] = value
+	}
+}
+
+// checkValues compares the effective values of a copy with the kept copy and
+// returns the conflict, or nil when both receive the same values
+func (d *Deduplicator) checkValues(kept, candidate string) (*ValuesConflict, error) {
+	if d.opts.ValuesCheck == ValuesCheckOff {
+		return nil, nil
+	}
+
+	if d.values == nil {
+		index, err := d.cachedValuesIndex()
+		if err != nil {
+			return nil, err
+		}
+		d.values = index
+	}
+
+	keys := divergentInstances(d.values.effectiveValues(kept), d.values.effectiveValues(candidate))
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	if len(keys) > maxReportedKeys {
+		keys = append(keys[:maxReportedKeys], fmt.Sprintf("... %d more", len(keys)-maxReportedKeys))
+	}
+
+	return &ValuesConflict{
+		Path:    candidate,
+		Keys:    keys,
+		Removed: d.opts.ValuesCheck != ValuesCheckRefuse,
+	}, nil
+}
@@ -0,0 +1,217 @@
+package diff
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/harness/helm-optimize/pkg/render"
+	"github.com/pmezard/go-difflib/difflib"
+	"gopkg.in/yaml.v3"
+)
+
+// metadataFiles are the chart files compared field by field
+var metadataFiles = []string{"Chart.yaml", "Chart.lock"}
+
+// compareSubcharts reports the dependency copies present in only one tree or
+// replaced by another version
+func compareSubcharts(before, after *tree) []SubchartChange {
+	var changes []SubchartChange
+	for _, p := range union(before.subcharts, after.subcharts) {
+		b, inBefore := before.subcharts[p]
+		a, inAfter := after.subcharts[p]
+		switch {
+		case !inBefore:
+			changes = append(changes, SubchartChange{Path: p, Change: ChangeAdded, After: a.Key(), Size: after.sizeBelow(p)})
+		case !inAfter:
+			changes = append(changes, SubchartChange{Path: p, Change: ChangeRemoved, Before: b.Key(), Size: before.sizeBelow(p)})
+		case a != b:
+			changes = append(changes, SubchartChange{Path: p, Change: ChangeModified, Before: b.Key(), After: a.Key()})
+		}
+	}
+
+	// Only report the top of an added or removed subtree
+	var out []SubchartChange
+	var reported []string
+	for _, c := range changes {
+		if c.Change != ChangeModified && withinAny(c.Path, reported) {
+			continue
+		}
+		if c.Change != ChangeModified {
+			reported = append(reported, c.Path)
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+// compareFiles reports the files whose content differs, skipping the given directories
+func compareFiles(before, after *tree, skip []string) []FileChange {
+	var changes []FileChange
+	for _, p := range union(before.files, after.files) {
+		if withinAny(p, skip) {
+			continue
+		}
+		b, inBefore := before.files[p]
+		a, inAfter := after.files[p]
+		change := FileChange{Path: p, Before: b.size, After: a.size, Delta: a.size - b.size}
+		switch {
+		case !inBefore:
+			change.Change = ChangeAdded
+		case !inAfter:
+			change.Change = ChangeRemoved
+		case a.hash != b.hash:
+			change.Change = ChangeModified
+		default:
+			continue
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+// compareMetadata compares the fields of the Chart.yaml and Chart.lock files
+// of every chart found in either tree, skipping the given directories
+func compareMetadata(before, after *tree, skip []string) ([]FieldChange, error) {
+	var changes []FieldChange
+	for _, p := range union(before.files, after.files) {
+		if withinAny(p, skip) || !contains(metadataFiles, path.Base(p)) {
+			continue
+		}
+		b, err := readFields(before.root, p)
+		if err != nil {
+			return nil, err
+		}
+		a, err := readFields(after.root, p)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, field := range union(b, a) {
+			bv, inBefore := b[field]
+			av, inAfter := a[field]
+			switch {
+			case !inBefore:
+				changes = append(changes, FieldChange{File: p, Field: field, Change: ChangeAdded, After: av})
+			case !inAfter:
+				changes = append(changes, FieldChange{File: p, Field: field, Change: ChangeRemoved, Before: bv})
+			case av != bv:
+				changes = append(changes, FieldChange{File: p, Field: field, Change: ChangeModified, Before: bv, After: av})
+			}
+		}
+	}
+	return changes, nil
+}
+
+// readFields flattens a YAML file into field paths and scalar values; a
+// missing file has no fields
+func readFields(root, rel string) (map[string]string, error) {
+	fields := map[string]string{}
+	data, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(rel)))
+	if os.IsNotExist(err) {
+		return fields, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", rel, err)
+	}
+	flatten("", doc, fields)
+	return fields, nil
+}
+
+// flatten records the scalar values below a YAML node under their field path
+func flatten(prefix string, node interface{}, fields map[string]string) {
+	switch v := node.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			if prefix == "" {
+				flatten(key, child, fields)
+			} else {
+				flatten(prefix+"."+key, child, fields)
+			}
+		}
+	case []interface{}:
+		for i, child := range v {
+			flatten(fmt.Sprintf("%s[%d]", prefix, i), child, fields)
+		}
+	case nil:
+		fields[prefix] = "null"
+	case string:
+		if v == "" {
+			v = `""`
+		}
+		fields[prefix] = v
+	default:
+		fields[prefix] = fmt.Sprint(v)
+	}
+}
+
+// compareManifests renders both charts and diffs the objects by identity
+func compareManifests(opts Options) ([]ManifestChange, error) {
+	before, err := renderObjects(opts.Before, opts.ValueFiles)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render %s: %w", opts.BeforeName, err)
+	}
+	after, err := renderObjects(opts.After, opts.ValueFiles)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render %s: %w", opts.AfterName, err)
+	}
+
+	var changes []ManifestChange
+	for _, id := range union(before, after) {
+		b, inBefore := before[id]
+		a, inAfter := after[id]
+		switch {
+		case !inBefore:
+			changes = append(changes, ManifestChange{Resource: id, Change: ChangeAdded, Template: a.Template})
+		case !inAfter:
+			changes = append(changes, ManifestChange{Resource: id, Change: ChangeRemoved, Template: b.Template})
+		case a.Content != b.Content:
+			diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+				A:        difflib.SplitLines(b.Content),
+				B:        difflib.SplitLines(a.Content),
+				FromFile: "before/" + b.Template,
+				ToFile:   "after/" + a.Template,
+				Context:  3,
+			})
+			if err != nil {
+				return nil, err
+			}
+			changes = append(changes, ManifestChange{Resource: id, Change: ChangeModified, Template: a.Template, Diff: diff})
+		}
+	}
+	return changes, nil
+}
+
+// renderObjects renders a chart and keys the manifests by apiVersion/kind/name,
+// in template order.
+// Objects rendered more than once get a numbered suffix, so duplicates
+// collapsed by an optimization show up as removed.
+func renderObjects(chartPath string, valueFiles []string) (map[string]render.Manifest, error) {
+	_, rendered, err := render.Load(render.Options{ChartPath: chartPath, ValueFiles: valueFiles})
+	if err != nil {
+		return nil, err
+	}
+	manifests, err := render.Split(rendered)
+	if err != nil {
+		return nil, err
+	}
+	objects := map[string]render.Manifest{}
+	for _, m := range manifests {
+		id := fmt.Sprintf("%s/%s/%s", m.APIVersion(), m.Kind(), m.Name())
+		key := id
+		for n := 2; ; n++ {
+			if _, taken := objects[key]; !taken {
+				break
+			}
+			key = fmt.Sprintf("%s#%d", id, n)
+		}
+		objects[key] = m
+	}
+	return objects, nil
+}
@@ -0,0 +1,112 @@
+package diff
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Output formats of the diff command
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// KnownFormats lists the supported output formats
+var KnownFormats = []string{FormatText, FormatJSON}
+
+// Kinds of change between the two charts
+const (
+	ChangeAdded    = "added"
+	ChangeRemoved  = "removed"
+	ChangeModified = "modified"
+)
+
+// Options defines the parameters for comparing two charts
+type Options struct {
+	// Before is the original chart directory
+	Before string
+	// After is the optimized chart directory
+	After string
+	// BeforeName and AfterName are shown in the report instead of the directories
+	BeforeName string
+	AfterName  string
+	// Render also compares the manifests rendered from both charts
+	Render bool
+	// ValueFiles are used to render the charts
+	ValueFiles []string
+	Format     string
+}
+
+// Run compares the two charts and writes the report to stdout
+func Run(opts Options) error {
+	if !contains(KnownFormats, opts.Format) {
+		return fmt.Errorf("unknown output format '%s' (known: %s)", opts.Format, strings.Join(KnownFormats, ", "))
+	}
+
+	report, err := Compare(opts)
+	if err != nil {
+		return err
+	}
+	return report.Write(os.Stdout, opts.Format)
+}
+
+// Compare builds the structural differences between the two charts
+func Compare(opts Options) (*Report, error) {
+	if opts.BeforeName == "" {
+		opts.BeforeName = opts.Before
+	}
+	if opts.AfterName == "" {
+		opts.AfterName = opts.After
+	}
+
+	before, err := loadTree(opts.Before)
+	if err != nil {
+		return nil, err
+	}
+	after, err := loadTree(opts.After)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{
+		Before: opts.BeforeName,
+		After:  opts.AfterName,
+		Size:   SizeChange{Before: before.size(), After: after.size()},
+	}
+	report.Size.Delta = report.Size.After - report.Size.Before
+	report.Subcharts = compareSubcharts(before, after)
+
+	// Files of added or removed subcharts are summarized by the subchart entry
+	var skip []string
+	for _, s := range report.Subcharts {
+		if s.Change != ChangeModified {
+			skip = append(skip, s.Path)
+		}
+	}
+	report.Files = compareFiles(before, after, skip)
+
+	report.Metadata, err = compareMetadata(before, after, skip)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Render {
+		report.Manifests, err = compareManifests(opts)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return report, nil
+}
+
+// contains reports whether the list holds the value
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
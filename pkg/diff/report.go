@@ -0,0 +1,164 @@
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/harness/helm-optimize/pkg/common"
+)
+
+// Report describes the differences between two charts
+type Report struct {
+	Before    string           `json:"before"`
+	After     string           `json:"after"`
+	Size      SizeChange       `json:"size"`
+	Subcharts []SubchartChange `json:"subcharts,omitempty"`
+	Metadata  []FieldChange    `json:"metadata,omitempty"`
+	Files     []FileChange     `json:"files,omitempty"`
+	Manifests []ManifestChange `json:"manifests,omitempty"`
+}
+
+// SizeChange is the total size of the chart files before and after
+type SizeChange struct {
+	Before int64 `json:"before"`
+	After  int64 `json:"after"`
+	Delta  int64 `json:"delta"`
+}
+
+// SubchartChange is a dependency copy added, removed or replaced by another version
+type SubchartChange struct {
+	// Path is the subchart directory relative to the chart root
+	Path   string `json:"path"`
+	Change string `json:"change"`
+	// Before and After are the name-version of the dependency
+	Before string `json:"before,omitempty"`
+	After  string `json:"after,omitempty"`
+	// Size is the size of the added or removed subchart files
+	Size int64 `json:"size,omitempty"`
+}
+
+// FieldChange is a changed field of a Chart.yaml or Chart.lock file
+type FieldChange struct {
+	// File is the metadata file relative to the chart root
+	File string `json:"file"`
+	// Field is the path of the field, e.g. dependencies[0].version
+	Field  string `json:"field"`
+	Change string `json:"change"`
+	Before string `json:"before,omitempty"`
+	After  string `json:"after,omitempty"`
+}
+
+// FileChange is a file added, removed or modified outside added and removed subcharts
+type FileChange struct {
+	Path   string `json:"path"`
+	Change string `json:"change"`
+	Before int64  `json:"before"`
+	After  int64  `json:"after"`
+	Delta  int64  `json:"delta"`
+}
+
+// ManifestChange is a rendered object added, removed or modified
+type ManifestChange struct {
+	// Resource identifies the object as apiVersion/kind/name
+	Resource string `json:"resource"`
+	Change   string `json:"change"`
+	// Template is the template producing the object
+	Template string `json:"template"`
+	// Diff is the unified diff of a modified object
+	Diff string `json:"diff,omitempty"`
+}
+
+// Empty reports whether the charts have the same content
+func (r *Report) Empty() bool {
+	return len(r.Subcharts) == 0 && len(r.Metadata) == 0 && len(r.Files) == 0 && len(r.Manifests) == 0
+}
+
+// Write writes the report in the given format
+func (r *Report) Write(w io.Writer, format string) error {
+	if format == FormatJSON {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		encoder.SetEscapeHTML(false)
+		return encoder.Encode(r)
+	}
+	r.writeText(w)
+	return nil
+}
+
+// writeText writes a section per kind of change
+func (r *Report) writeText(w io.Writer) {
+	fmt.Fprintf(w, "Comparing %s with %s\n", r.Before, r.After)
+	fmt.Fprintf(w, "Size: %s -> %s (%s)\n", common.FormatSize(r.Size.Before), common.FormatSize(r.Size.After), formatDelta(r.Size.Delta))
+	if r.Empty() {
+		fmt.Fprintln(w, "No differences found.")
+		return
+	}
+
+	if len(r.Subcharts) > 0 {
+		fmt.Fprintf(w, "\nSubcharts:\n")
+		for _, s := range r.Subcharts {
+			switch s.Change {
+			case ChangeAdded:
+				fmt.Fprintf(w, "  + %s %s (%s)\n", s.Path, s.After, common.FormatSize(s.Size))
+			case ChangeRemoved:
+				fmt.Fprintf(w, "  - %s %s (%s)\n", s.Path, s.Before, common.FormatSize(s.Size))
+			default:
+				fmt.Fprintf(w, "  ~ %s %s -> %s\n", s.Path, s.Before, s.After)
+			}
+		}
+	}
+
+	if len(r.Metadata) > 0 {
+		fmt.Fprintf(w, "\nChart metadata:\n")
+		for _, f := range r.Metadata {
+			switch f.Change {
+			case ChangeAdded:
+				fmt.Fprintf(w, "  + %s %s: %s\n", f.File, f.Field, f.After)
+			case ChangeRemoved:
+				fmt.Fprintf(w, "  - %s %s: %s\n", f.File, f.Field, f.Before)
+			default:
+				fmt.Fprintf(w, "  ~ %s %s: %s -> %s\n", f.File, f.Field, f.Before, f.After)
+			}
+		}
+	}
+
+	if len(r.Files) > 0 {
+		fmt.Fprintf(w, "\nFiles:\n")
+		for _, f := range r.Files {
+			fmt.Fprintf(w, "  %s %-50s %10s -> %-10s %s\n", changeMark(f.Change), f.Path, common.FormatSize(f.Before), common.FormatSize(f.After), formatDelta(f.Delta))
+		}
+	}
+
+	if len(r.Manifests) > 0 {
+		fmt.Fprintf(w, "\nManifests:\n")
+		for _, m := range r.Manifests {
+			fmt.Fprintf(w, "  %s %s (%s)\n", changeMark(m.Change), m.Resource, m.Template)
+		}
+		for _, m := range r.Manifests {
+			if m.Diff != "" {
+				fmt.Fprintf(w, "\n%s", m.Diff)
+			}
+		}
+	}
+}
+
+// changeMark returns the prefix of a change in text output
+func changeMark(change string) string {
+	switch change {
+	case ChangeAdded:
+		return "+"
+	case ChangeRemoved:
+		return "-"
+	default:
+		return "~"
+	}
+}
+
+// formatDelta renders a signed byte difference
+func formatDelta(delta int64) string {
+	if delta < 0 {
+		return "-" + common.FormatSize(-delta)
+	}
+	return "+" + common.FormatSize(delta)
+}
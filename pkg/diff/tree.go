@@ -0,0 +1,122 @@
+package diff
+
+import (
+	"crypto/sha256"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/harness/helm-optimize/pkg/dedup"
+)
+
+// file is a regular file of a chart tree
+type file struct {
+	size int64
+	hash [sha256.Size]byte
+}
+
+// tree is the content of a chart directory, keyed by slash separated paths
+// relative to the chart root
+type tree struct {
+	root string
+	// subcharts maps the path of every unpacked dependency to its name-version
+	subcharts map[string]dedup.Dependency
+	files     map[string]file
+}
+
+// loadTree collects the subcharts with the deduplication traversal and hashes every file
+func loadTree(root string) (*tree, error) {
+	if _, err := os.Stat(filepath.Join(root, "Chart.yaml")); err != nil {
+		return nil, err
+	}
+
+	deps, err := dedup.NewDeduplicator(dedup.Options{ChartPath: root}).Dependencies(root)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &tree{root: root, subcharts: map[string]dedup.Dependency{}, files: map[string]file{}}
+	for _, copies := range deps {
+		for _, cp := range copies {
+			t.subcharts[t.rel(cp.Path)] = cp.Dependency
+		}
+	}
+
+	err = filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || !entry.Type().IsRegular() {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		t.files[t.rel(path)] = file{size: int64(len(data)), hash: sha256.Sum256(data)}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// rel returns the slash separated path relative to the chart root
+func (t *tree) rel(path string) string {
+	rel, err := filepath.Rel(t.root, path)
+	if err != nil {
+		return path
+	}
+	return filepath.ToSlash(rel)
+}
+
+// size returns the total size of the files
+func (t *tree) size() int64 {
+	var size int64
+	for _, f := range t.files {
+		size += f.size
+	}
+	return size
+}
+
+// sizeBelow returns the total size of the files below a directory
+func (t *tree) sizeBelow(dir string) int64 {
+	var size int64
+	for path, f := range t.files {
+		if within(path, dir) {
+			size += f.size
+		}
+	}
+	return size
+}
+
+// within reports whether the path is, or lies below, the directory
+func within(path, dir string) bool {
+	return path == dir || strings.HasPrefix(path, dir+"/")
+}
+
+// withinAny reports whether the path lies below one of the directories
+func withinAny(path string, dirs []string) bool {
+	for _, dir := range dirs {
+		if within(path, dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// union returns the sorted keys of both maps
+func union[V any](a, b map[string]V) []string {
+	seen := map[string]bool{}
+	var keys []string
+	for _, m := range []map[string]V{a, b} {
+		for key := range m {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,440 @@
+package helpers
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/harness/helm-optimize/pkg/common"
+	"github.com/harness/helm-optimize/pkg/render"
+	"gopkg.in/yaml.v3"
+)
+
+// Analyzer finds named templates defined with identical bodies across a chart tree
+type Analyzer struct {
+	opts     Options
+	selector common.Selector
+	// root is the absolute chart path
+	root string
+	// defines holds every define block found, in traversal order
+	defines []Definition
+	// changes lists the files the consolidation rewrites
+	changes []common.Change
+}
+
+// Definition is a named template defined in a chart of the tree
+type Definition struct {
+	Name string
+	// File is the template file, relative to the chart root
+	File string
+	define
+}
+
+// Group is a set of named templates with identical bodies
+type Group struct {
+	// Shared is the name of the consolidated template in the library chart
+	Shared      string
+	Definitions []Definition
+	// Size is the size of one define block in bytes
+	Size int
+	// Savings is the number of bytes removed by consolidating the group;
+	// zero or less when the wrappers would cost more than the bodies
+	Savings int
+}
+
+// NewAnalyzer creates a new Analyzer
+func NewAnalyzer(opts Options) *Analyzer {
+	return &Analyzer{opts: opts}
+}
+
+// Analyze reports the duplicated helpers, checks that consolidating them keeps
+// the rendered output unchanged and applies the consolidation when requested
+func (a *Analyzer) Analyze() error {
+	root, err := filepath.Abs(a.opts.ChartPath)
+	if err != nil {
+		return fmt.Errorf("failed to get absolute path: %w", err)
+	}
+	a.root = root
+	a.selector = common.Selector{Root: root, Exclude: a.opts.Exclude}
+
+	metadata := common.ReadChartMetadata(root)
+	if metadata == nil {
+		return fmt.Errorf("no Chart.yaml found in '%s'", a.opts.ChartPath)
+	}
+	library := a.opts.Library
+	if library == "" {
+		library = metadata.Name + "-helpers"
+	}
+
+	if err := a.collect(root); err != nil {
+		return err
+	}
+
+	groups := a.groups(library)
+	a.printReport(groups)
+
+	var consolidated []Group
+	for _, g := range groups {
+		if g.Savings > 0 {
+			consolidated = append(consolidated, g)
+		}
+	}
+	if len(consolidated) == 0 {
+		fmt.Println("No helpers worth consolidating.")
+		return nil
+	}
+
+	// Consolidate a copy of the chart first and compare the rendered output
+	if err := a.verify(library, metadata.Version, consolidated); err != nil {
+		return err
+	}
+	fmt.Println("Rendering equivalence check passed: consolidated chart renders identical manifests.")
+	a.changes = a.plan(library, consolidated)
+
+	if !a.opts.Consolidate || a.opts.Check {
+		return nil
+	}
+	if err := a.apply(root, library, metadata.Version, consolidated); err != nil {
+		return err
+	}
+	fmt.Printf("Consolidation completed. %d helpers moved to library chart %s.\n", len(consolidated), filepath.Join(root, "charts", library))
+	return nil
+}
+
+// collect parses the define blocks of every template of the chart and its unpacked subcharts
+func (a *Analyzer) collect(chartPath string) error {
+	templates := filepath.Join(chartPath, "templates")
+	if _, err := os.Stat(templates); err == nil {
+		err := filepath.WalkDir(templates, func(path string, entry os.DirEntry, err error) error {
+			if err != nil || entry.IsDir() {
+				return err
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			defines, err := parseDefines(string(data))
+			if err != nil {
+				return fmt.Errorf("failed to parse %s: %w", path, err)
+			}
+			rel, err := filepath.Rel(a.root, path)
+			if err != nil {
+				return err
+			}
+			for _, d := range defines {
+				a.defines = append(a.defines, Definition{Name: d.Name, File: rel, define: d})
+			}
+			if a.opts.Verbose && len(defines) > 0 {
+				fmt.Printf("  Found %d named templates in %s\n", len(defines), path)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	entries, err := os.ReadDir(filepath.Join(chartPath, "charts"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	for _, entry := range entries {
+		subChartPath := filepath.Join(chartPath, "charts", entry.Name())
+		if !entry.IsDir() || a.selector.Excluded(subChartPath) {
+			continue
+		}
+		if err := a.collect(subChartPath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// groups returns the sets of definitions whose blocks are identical apart from the name
+func (a *Analyzer) groups(library string) []Group {
+	var order []string
+	byBody := map[string][]Definition{}
+	for _, d := range a.defines {
+		key := d.Block[:d.nameStart] + "\x00" + d.Block[d.nameEnd:]
+		if _, ok := byBody[key]; !ok {
+			order = append(order, key)
+		}
+		byBody[key] = append(byBody[key], d)
+	}
+
+	var groups []Group
+	for _, key := range order {
+		defs := byBody[key]
+		if len(defs) < 2 {
+			continue
+		}
+		g := Group{
+			Shared:      library + "." + defs[0].Name,
+			Definitions: defs,
+			Size:        len(defs[0].Block),
+		}
+		g.Savings = len(defs)*g.Size - len(sharedBlock(g))
+		for _, d := range defs {
+			g.Savings -= len(wrapper(d.define, d.Name, g.Shared))
+		}
+		groups = append(groups, g)
+	}
+	return groups
+}
+
+// printReport lists the duplicated helpers and the bytes consolidation saves
+func (a *Analyzer) printReport(groups []Group) {
+	total := 0
+	for _, g := range groups {
+		fmt.Printf("Identical helper (%d bytes) defined %d times:\n", g.Size, len(g.Definitions))
+		for _, d := range g.Definitions {
+			fmt.Printf("  %-40s %s\n", strconv.Quote(d.Name), d.File)
+		}
+		if g.Savings > 0 {
+			fmt.Printf("  consolidating as %s saves %d bytes\n", strconv.Quote(g.Shared), g.Savings)
+			total += g.Savings
+		} else {
+			fmt.Println("  too small to consolidate")
+		}
+	}
+	fmt.Printf("Found %d duplicated helpers in %d named templates; consolidation saves %d bytes.\n", len(groups), len(a.defines), total)
+}
+
+// verify consolidates a temporary copy of the chart and checks that it
+// renders exactly the same manifests as the original
+func (a *Analyzer) verify(library, version string, groups []Group) error {
+	tempDir, err := os.MkdirTemp("", "helm-optimize-")
+	if err != nil {
+		return fmt.Errorf("failed to create workspace: %w", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	copyPath := filepath.Join(tempDir, filepath.Base(a.root))
+	if err := common.CopyDir(a.root, copyPath); err != nil {
+		return fmt.Errorf("failed to copy chart: %w", err)
+	}
+	if err := a.apply(copyPath, library, version, groups); err != nil {
+		return err
+	}
+
+	_, before, err := render.Load(render.Options{ChartPath: a.root, ValueFiles: a.opts.ValueFiles})
+	if err != nil {
+		return fmt.Errorf("failed to render original chart: %w", err)
+	}
+	_, after, err := render.Load(render.Options{ChartPath: copyPath, ValueFiles: a.opts.ValueFiles})
+	if err != nil {
+		return fmt.Errorf("failed to render consolidated chart: %w", err)
+	}
+
+	if !reflect.DeepEqual(before, after) {
+		var changed []string
+		for name, content := range before {
+			if after[name] != content {
+				changed = append(changed, name)
+			}
+		}
+		for name := range after {
+			if _, ok := before[name]; !ok {
+				changed = append(changed, name)
+			}
+		}
+		sort.Strings(changed)
+		return fmt.Errorf("%w: rendering equivalence check failed, consolidation changes the output of: %s", common.ErrVerificationFailed, strings.Join(changed, ", "))
+	}
+	return nil
+}
+
+// plan lists the files consolidating the groups writes: the templates holding
+// the definitions, the helpers of the library chart and, for a new library
+// chart, its Chart.yaml and the root Chart.yaml declaring it
+func (a *Analyzer) plan(library string, groups []Group) []common.Change {
+	files := map[string]bool{}
+	for _, g := range groups {
+		for _, d := range g.Definitions {
+			files[d.File] = true
+		}
+	}
+	var paths []string
+	for file := range files {
+		paths = append(paths, filepath.Join(a.root, file))
+	}
+	sort.Strings(paths)
+
+	dir := filepath.Join(a.root, "charts", library)
+	paths = append(paths, filepath.Join(dir, "templates", "_helpers.tpl"))
+	if _, err := os.Stat(dir); err != nil {
+		paths = append(paths, filepath.Join(dir, "Chart.yaml"), filepath.Join(a.root, "Chart.yaml"))
+	}
+
+	var changes []common.Change
+	for _, path := range paths {
+		changes = append(changes, common.Change{Action: common.ActionRewrite, Path: path})
+	}
+	return changes
+}
+
+// Changes returns the files the consolidation rewrites, or would when it is
+// not applied
+func (a *Analyzer) Changes() []common.Change {
+	return a.changes
+}
+
+// rewrite replaces a definition with a wrapper around a shared template
+type rewrite struct {
+	d      Definition
+	shared string
+}
+
+// apply rewrites the duplicated definitions of the chart at root as wrappers
+// around the shared templates and creates the library chart holding them
+func (a *Analyzer) apply(root, library, version string, groups []Group) error {
+	byFile := map[string][]rewrite{}
+	var shared []string
+	for _, g := range groups {
+		shared = append(shared, sharedBlock(g))
+		for _, d := range g.Definitions {
+			byFile[d.File] = append(byFile[d.File], rewrite{d: d, shared: g.Shared})
+		}
+	}
+
+	for file, defs := range byFile {
+		path := filepath.Join(root, file)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		// Replace from the end so earlier offsets stay valid
+		sort.Slice(defs, func(i, j int) bool { return defs[i].d.Start > defs[j].d.Start })
+		text := string(data)
+		for _, entry := range defs {
+			text = text[:entry.d.Start] + wrapper(entry.d.define, entry.d.Name, entry.shared) + text[entry.d.End:]
+		}
+		if a.opts.Verbose && root == a.root {
+			fmt.Printf("Rewriting %d helpers in %s\n", len(defs), path)
+		}
+		if err := os.WriteFile(path, []byte(text), 0644); err != nil {
+			return fmt.Errorf("failed to update %s: %w", path, err)
+		}
+	}
+
+	return writeLibrary(root, library, version, shared)
+}
+
+// sharedBlock returns the group's define block under its shared name
+func sharedBlock(g Group) string {
+	d := g.Definitions[0]
+	return d.Block[:d.nameStart] + strconv.Quote(g.Shared) + d.Block[d.nameEnd:]
+}
+
+// wrapper returns a define block delegating to the shared template. The outer
+// trim markers of the original block are kept so the surrounding text is unchanged.
+func wrapper(d define, name, shared string) string {
+	left, right := "{{ ", " }}"
+	if d.LeftTrim {
+		left = "{{- "
+	}
+	if d.RightTrim {
+		right = " -}}"
+	}
+	return fmt.Sprintf("%sdefine %s -}}{{ include %s . }}{{- end%s", left, strconv.Quote(name), strconv.Quote(shared), right)
+}
+
+// writeLibrary creates the library chart in the root chart's charts/ directory
+// and declares it as a dependency of the root chart
+func writeLibrary(root, library, version string, blocks []string) error {
+	dir := filepath.Join(root, "charts", library)
+	helpers := strings.Join(blocks, "\n\n") + "\n"
+
+	// A library chart from an earlier consolidation receives the new helpers
+	if _, err := os.Stat(dir); err == nil {
+		if meta := common.ReadChartMetadata(dir); meta == nil || meta.Name != library {
+			return fmt.Errorf("directory '%s' exists and is not the library chart %s", dir, library)
+		}
+		path := filepath.Join(dir, "templates", "_helpers.tpl")
+		existing, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read library chart: %w", err)
+		}
+		return os.WriteFile(path, append(existing, []byte("\n"+helpers)...), 0644)
+	}
+
+	if err := os.MkdirAll(filepath.Join(dir, "templates"), 0755); err != nil {
+		return fmt.Errorf("failed to create library chart: %w", err)
+	}
+
+	chartYaml, err := yaml.Marshal(map[string]interface{}{
+		"apiVersion":  "v2",
+		"name":        library,
+		"description": "Named templates shared by the subcharts",
+		"type":        "library",
+		"version":     version,
+	})
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "Chart.yaml"), chartYaml, 0644); err != nil {
+		return fmt.Errorf("failed to write library chart: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "templates", "_helpers.tpl"), []byte(helpers), 0644); err != nil {
+		return fmt.Errorf("failed to write library chart: %w", err)
+	}
+
+	return addDependency(root, library, version)
+}
+
+// addDependency declares an unpacked subchart in the chart's Chart.yaml,
+// preserving the rest of the file. Without a repository Helm expects the
+// subchart in the charts/ directory.
+func addDependency(root, name, version string) error {
+	path := filepath.Join(root, "Chart.yaml")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return fmt.Errorf("failed to parse %s: not a mapping", path)
+	}
+
+	entry := &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{
+		{Kind: yaml.ScalarNode, Value: "name"}, {Kind: yaml.ScalarNode, Value: name},
+		{Kind: yaml.ScalarNode, Value: "version"}, {Kind: yaml.ScalarNode, Value: version, Style: yaml.DoubleQuotedStyle},
+	}}
+
+	chart := doc.Content[0]
+	var deps *yaml.Node
+	for i := 0; i+1 < len(chart.Content); i += 2 {
+		if chart.Content[i].Value == "dependencies" {
+			deps = chart.Content[i+1]
+		}
+	}
+	if deps == nil {
+		deps = &yaml.Node{Kind: yaml.SequenceNode}
+		chart.Content = append(chart.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: "dependencies"}, deps)
+	}
+	deps.Kind = yaml.SequenceNode
+	deps.Content = append(deps.Content, entry)
+
+	var out bytes.Buffer
+	enc := yaml.NewEncoder(&out)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, out.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to update %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package helpers
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/harness/helm-optimize/pkg/common"
+)
+
+// Options defines the parameters for the helper template analysis
+type Options struct {
+	ChartPath string
+	// Consolidate moves duplicated helpers into a shared library chart
+	Consolidate bool
+	// Check fails with common.ErrNotOptimized when helpers would be
+	// consolidated; the consolidation is verified but not applied
+	Check bool
+	// Library is the name of the shared library chart (default: <chart name>-helpers)
+	Library string
+	// ValueFiles are used to render the chart for the equivalence check
+	ValueFiles []string
+	Verbose    bool
+	// Exclude skips subchart paths matching these globs or chart names
+	Exclude []string
+}
+
+// Run analyzes the helper templates of the chart and, when requested,
+// consolidates the duplicates
+func Run(opts Options) error {
+	fmt.Printf("Analyzing helper templates of chart at '%s'...\n", opts.ChartPath)
+
+	analyzer := NewAnalyzer(opts)
+	if err := analyzer.Analyze(); err != nil {
+		return err
+	}
+
+	if opts.Check {
+		return common.CheckChanges(os.Stdout, "helpers", opts.ChartPath, analyzer.Changes())
+	}
+	return nil
+}
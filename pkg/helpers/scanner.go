@@ -0,0 +1,206 @@
+package helpers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// define is a named template definition found in a template file
+type define struct {
+	Name string
+	// Start and End delimit the whole block, from the define action to the end action
+	Start int
+	End   int
+	// Block is the source of the whole block
+	Block string
+	// LeftTrim and RightTrim record the trim markers facing the surrounding text
+	LeftTrim  bool
+	RightTrim bool
+	// nameStart and nameEnd delimit the quoted name inside Block
+	nameStart int
+	nameEnd   int
+}
+
+// action is a single {{ ... }} action of a template
+type action struct {
+	start, end int
+	keyword    string
+	args       string
+	// offset of args within the template text
+	argsStart           int
+	leftTrim, rightTrim bool
+}
+
+// blockKeywords are the actions closed by {{ end }}
+var blockKeywords = map[string]bool{"if": true, "range": true, "with": true, "define": true, "block": true}
+
+// parseDefines returns the top level define blocks of a template. It only
+// tracks action nesting, so templates need not be valid for the Helm engine.
+func parseDefines(text string) ([]define, error) {
+	type open struct {
+		action action
+	}
+	var stack []open
+	var defines []define
+
+	for pos := 0; ; {
+		act, ok, err := nextAction(text, pos)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			break
+		}
+		pos = act.end
+
+		switch {
+		case blockKeywords[act.keyword]:
+			stack = append(stack, open{action: act})
+		case act.keyword == "end":
+			if len(stack) == 0 {
+				return nil, fmt.Errorf("unexpected {{end}} at offset %d", act.start)
+			}
+			opened := stack[len(stack)-1].action
+			stack = stack[:len(stack)-1]
+			if opened.keyword != "define" || len(stack) != 0 {
+				continue
+			}
+
+			name, nameLen, err := quotedName(opened.args)
+			if err != nil {
+				return nil, fmt.Errorf("invalid define at offset %d: %w", opened.start, err)
+			}
+			nameStart := opened.argsStart - opened.start
+			defines = append(defines, define{
+				Name:      name,
+				Start:     opened.start,
+				End:       act.end,
+				Block:     text[opened.start:act.end],
+				LeftTrim:  opened.leftTrim,
+				RightTrim: act.rightTrim,
+				nameStart: nameStart,
+				nameEnd:   nameStart + nameLen,
+			})
+		}
+	}
+
+	if len(stack) != 0 {
+		return nil, fmt.Errorf("unclosed {{%s}} at offset %d", stack[len(stack)-1].action.keyword, stack[len(stack)-1].action.start)
+	}
+	return defines, nil
+}
+
+// nextAction finds the next action at or after pos, skipping comments
+func nextAction(text string, pos int) (action, bool, error) {
+	for {
+		i := strings.Index(text[pos:], "{{")
+		if i < 0 {
+			return action{}, false, nil
+		}
+		start := pos + i
+		act := action{start: start}
+
+		j := start + 2
+		if strings.HasPrefix(text[j:], "- ") {
+			act.leftTrim = true
+			j += 2
+		}
+		for j < len(text) && isSpace(text[j]) {
+			j++
+		}
+
+		// Comments may contain anything but their terminator
+		if strings.HasPrefix(text[j:], "/*") {
+			k := strings.Index(text[j:], "*/")
+			if k < 0 {
+				return action{}, false, fmt.Errorf("unclosed comment at offset %d", start)
+			}
+			k = j + k + 2
+			e := strings.Index(text[k:], "}}")
+			if e < 0 {
+				return action{}, false, fmt.Errorf("unclosed action at offset %d", start)
+			}
+			pos = k + e + 2
+			continue
+		}
+
+		end, err := actionEnd(text, j)
+		if err != nil {
+			return action{}, false, fmt.Errorf("%w at offset %d", err, start)
+		}
+		act.end = end + 2
+
+		body := text[j:end]
+		if strings.HasSuffix(body, " -") {
+			act.rightTrim = true
+			body = body[:len(body)-2]
+		}
+		body = strings.TrimRight(body, " \t\r\n")
+
+		keyword := body
+		if k := strings.IndexAny(body, " \t\r\n"); k >= 0 {
+			keyword = body[:k]
+		}
+		act.keyword = keyword
+		rest := body[len(keyword):]
+		trimmed := strings.TrimLeft(rest, " \t\r\n")
+		act.args = trimmed
+		act.argsStart = j + len(keyword) + len(rest) - len(trimmed)
+		return act, true, nil
+	}
+}
+
+// actionEnd returns the offset of the "}}" closing the action, skipping string
+// and character literals
+func actionEnd(text string, pos int) (int, error) {
+	for i := pos; i < len(text); i++ {
+		switch c := text[i]; c {
+		case '"', '\'':
+			for i++; i < len(text) && text[i] != c; i++ {
+				if text[i] == '\\' {
+					i++
+				}
+			}
+		case '`':
+			k := strings.IndexByte(text[i+1:], '`')
+			if k < 0 {
+				return 0, fmt.Errorf("unterminated raw string")
+			}
+			i += k + 1
+		case '}':
+			if strings.HasPrefix(text[i:], "}}") {
+				return i, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("unclosed action")
+}
+
+// quotedName parses the leading string literal of the define arguments and
+// returns its value and length in the source
+func quotedName(args string) (string, int, error) {
+	if args == "" {
+		return "", 0, fmt.Errorf("missing template name")
+	}
+	quote := args[0]
+	if quote != '"' && quote != '`' {
+		return "", 0, fmt.Errorf("template name must be a string literal")
+	}
+	for i := 1; i < len(args); i++ {
+		if quote == '"' && args[i] == '\\' {
+			i++
+			continue
+		}
+		if args[i] == quote {
+			name, err := strconv.Unquote(args[:i+1])
+			return name, i + 1, err
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated template name")
+}
+
+// isSpace reports whether the byte is template whitespace
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n'
+}
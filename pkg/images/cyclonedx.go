@@ -0,0 +1,104 @@
+package images
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// cycloneDXSpecVersion is the CycloneDX specification version produced
+const cycloneDXSpecVersion = "1.5"
+
+// BOM is a minimal CycloneDX bill of materials listing container images
+type BOM struct {
+	BOMFormat   string      `json:"bomFormat"`
+	SpecVersion string      `json:"specVersion"`
+	Version     int         `json:"version"`
+	Metadata    BOMMetadata `json:"metadata"`
+	Components  []Component `json:"components"`
+}
+
+// BOMMetadata describes the chart the BOM was produced for
+type BOMMetadata struct {
+	Tools     BOMTools  `json:"tools"`
+	Component Component `json:"component"`
+}
+
+// BOMTools lists the tools that produced the BOM
+type BOMTools struct {
+	Components []Component `json:"components"`
+}
+
+// Component is a CycloneDX component
+type Component struct {
+	Type       string     `json:"type"`
+	BOMRef     string     `json:"bom-ref,omitempty"`
+	Name       string     `json:"name"`
+	Version    string     `json:"version,omitempty"`
+	Purl       string     `json:"purl,omitempty"`
+	Properties []Property `json:"properties,omitempty"`
+}
+
+// Property is a CycloneDX name/value property
+type Property struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// CycloneDX converts the inventory to a CycloneDX BOM with one container
+// component per image, annotated with the subcharts that deploy it
+func (inv *Inventory) CycloneDX() BOM {
+	bom := BOM{
+		BOMFormat:   "CycloneDX",
+		SpecVersion: cycloneDXSpecVersion,
+		Version:     1,
+		Metadata: BOMMetadata{
+			Tools: BOMTools{Components: []Component{{Type: "application", Name: "helm-optimize"}}},
+			Component: Component{
+				Type:    "application",
+				BOMRef:  fmt.Sprintf("chart:%s@%s", inv.Chart, inv.Version),
+				Name:    inv.Chart,
+				Version: inv.Version,
+			},
+		},
+		Components: []Component{},
+	}
+
+	for _, img := range inv.Images {
+		repository, tag, digest := splitReference(img.Reference)
+		version := tag
+		if digest != "" {
+			version = digest
+		}
+
+		component := Component{
+			Type:    "container",
+			BOMRef:  img.Reference,
+			Name:    repository,
+			Version: version,
+			Purl:    purl(repository, tag, digest),
+		}
+		for _, subchart := range img.subcharts(inv.Chart) {
+			component.Properties = append(component.Properties, Property{Name: "helm-optimize:subchart", Value: subchart})
+		}
+		bom.Components = append(bom.Components, component)
+	}
+	return bom
+}
+
+// purl builds the package URL of an image: pkg:oci/<name>@<digest>?repository_url=<repository>&tag=<tag>
+func purl(repository, tag, digest string) string {
+	name := strings.ToLower(path.Base(repository))
+	purl := "pkg:oci/" + name
+	if digest != "" {
+		purl += "@" + url.PathEscape(digest)
+	}
+
+	query := url.Values{}
+	query.Set("repository_url", repository)
+	if tag != "" {
+		query.Set("tag", tag)
+	}
+	return purl + "?" + query.Encode()
+}
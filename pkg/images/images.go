@@ -0,0 +1,120 @@
+package images
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/harness/helm-optimize/pkg/render"
+)
+
+// Output formats supported by the images command
+const (
+	FormatText      = "text"
+	FormatJSON      = "json"
+	FormatCycloneDX = "cyclonedx"
+)
+
+// KnownFormats lists the supported output formats
+var KnownFormats = []string{FormatText, FormatJSON, FormatCycloneDX}
+
+// Options defines the parameters for the image inventory
+type Options struct {
+	ChartPath   string
+	ValueFiles  []string
+	ReleaseName string
+	Namespace   string
+	Format      string
+	Verbose     bool
+}
+
+// Image is a container image with every place that references it
+type Image struct {
+	Reference string   `json:"image"`
+	Sources   []Source `json:"sources"`
+}
+
+// Source identifies the container that references an image
+type Source struct {
+	// Subchart is the subchart chain producing the workload, empty for the root chart
+	Subchart  string `json:"subchart"`
+	Template  string `json:"template"`
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Container string `json:"container"`
+	// Init is true for init containers
+	Init bool `json:"init,omitempty"`
+}
+
+// Run renders the chart and writes the image inventory to stdout
+func Run(opts Options) error {
+	if !contains(KnownFormats, opts.Format) {
+		return fmt.Errorf("unknown output format '%s' (known: %s)", opts.Format, strings.Join(KnownFormats, ", "))
+	}
+
+	inventory, err := Build(opts)
+	if err != nil {
+		return err
+	}
+	return inventory.Write(os.Stdout, opts.Format)
+}
+
+// Inventory is the list of images deployed by a chart
+type Inventory struct {
+	Chart   string  `json:"chart"`
+	Version string  `json:"version"`
+	Images  []Image `json:"images"`
+}
+
+// Build renders the chart tree and collects the de-duplicated images, sorted by reference
+func Build(opts Options) (*Inventory, error) {
+	c, rendered, err := render.Load(render.Options{
+		ChartPath:   opts.ChartPath,
+		ValueFiles:  opts.ValueFiles,
+		ReleaseName: opts.ReleaseName,
+		Namespace:   opts.Namespace,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	manifests, err := render.Split(rendered)
+	if err != nil {
+		return nil, err
+	}
+
+	byRef := map[string]*Image{}
+	for _, m := range manifests {
+		for _, ref := range podImages(m) {
+			img, ok := byRef[ref.image]
+			if !ok {
+				img = &Image{Reference: ref.image}
+				byRef[ref.image] = img
+			}
+			img.Sources = append(img.Sources, Source{
+				Subchart:  m.Subchart,
+				Template:  m.Template,
+				Kind:      m.Kind(),
+				Name:      m.Name(),
+				Container: ref.container,
+				Init:      ref.init,
+			})
+		}
+	}
+
+	inventory := &Inventory{Chart: c.Metadata.Name, Version: c.Metadata.Version, Images: []Image{}}
+	for _, img := range byRef {
+		inventory.Images = append(inventory.Images, *img)
+	}
+	sort.Slice(inventory.Images, func(i, j int) bool {
+		return inventory.Images[i].Reference < inventory.Images[j].Reference
+	})
+
+	// Progress goes to stderr so it never mixes with machine readable output
+	if opts.Verbose {
+		fmt.Fprintf(os.Stderr, "Found %d images in %d manifests\n", len(inventory.Images), len(manifests))
+	}
+
+	return inventory, nil
+}
@@ -0,0 +1,88 @@
+package images
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Write writes the inventory in the given format
+func (inv *Inventory) Write(w io.Writer, format string) error {
+	switch format {
+	case FormatJSON:
+		return writeJSON(w, inv)
+	case FormatCycloneDX:
+		return writeJSON(w, inv.CycloneDX())
+	default:
+		inv.writeText(w)
+		return nil
+	}
+}
+
+// writeText writes one line per image followed by the workloads using it
+func (inv *Inventory) writeText(w io.Writer) {
+	fmt.Fprintf(w, "Images deployed by %s %s: %d\n", inv.Chart, inv.Version, len(inv.Images))
+	for _, img := range inv.Images {
+		fmt.Fprintf(w, "%s\n", img.Reference)
+		for _, src := range img.Sources {
+			subchart := src.Subchart
+			if subchart == "" {
+				subchart = inv.Chart
+			}
+			container := src.Container
+			if src.Init {
+				container += " (init)"
+			}
+			fmt.Fprintf(w, "  %-20s %s/%s container %s (%s)\n", subchart, src.Kind, src.Name, container, src.Template)
+		}
+	}
+}
+
+// writeJSON writes the value as indented JSON
+func writeJSON(w io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(v)
+}
+
+// contains reports whether the list holds the value
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// subcharts returns the distinct subcharts referencing the image
+func (img Image) subcharts(root string) []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, src := range img.Sources {
+		name := src.Subchart
+		if name == "" {
+			name = root
+		}
+		if !seen[name] {
+			seen[name] = true
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+// splitReference splits an image reference into repository, tag and digest
+func splitReference(ref string) (repository, tag, digest string) {
+	repository = ref
+	if i := strings.Index(repository, "@"); i >= 0 {
+		repository, digest = repository[:i], repository[i+1:]
+	}
+	// A colon after the last slash separates the tag; earlier colons belong to a registry port
+	if i := strings.LastIndex(repository, ":"); i > strings.LastIndex(repository, "/") {
+		repository, tag = repository[:i], repository[i+1:]
+	}
+	return repository, tag, digest
+}
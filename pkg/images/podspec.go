@@ -0,0 +1,72 @@
+package images
+
+import "github.com/harness/helm-optimize/pkg/render"
+
+// podSpecPaths maps workload kinds to the location of their pod spec
+var podSpecPaths = map[string][]string{
+	"Pod":                   {"spec"},
+	"Deployment":            {"spec", "template", "spec"},
+	"StatefulSet":           {"spec", "template", "spec"},
+	"DaemonSet":             {"spec", "template", "spec"},
+	"ReplicaSet":            {"spec", "template", "spec"},
+	"ReplicationController": {"spec", "template", "spec"},
+	"Job":                   {"spec", "template", "spec"},
+	"CronJob":               {"spec", "jobTemplate", "spec", "template", "spec"},
+}
+
+// containerLists are the pod spec fields holding containers, with whether
+// they run before the main containers
+var containerLists = []struct {
+	field string
+	init  bool
+}{
+	{"initContainers", true},
+	{"containers", false},
+	{"ephemeralContainers", false},
+}
+
+// imageRef is an image used by a single container
+type imageRef struct {
+	image     string
+	container string
+	init      bool
+}
+
+// podImages returns the images referenced by the pod spec of a workload manifest
+func podImages(m render.Manifest) []imageRef {
+	path, ok := podSpecPaths[m.Kind()]
+	if !ok {
+		return nil
+	}
+
+	var spec interface{} = m.Object
+	for _, key := range path {
+		obj, ok := spec.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		spec = obj[key]
+	}
+	podSpec, ok := spec.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	var refs []imageRef
+	for _, list := range containerLists {
+		containers, _ := podSpec[list.field].([]interface{})
+		for _, item := range containers {
+			container, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			image, _ := container["image"].(string)
+			if image == "" {
+				continue
+			}
+			name, _ := container["name"].(string)
+			refs = append(refs, imageRef{image: image, container: name, init: list.init})
+		}
+	}
+	return refs
+}
@@ -0,0 +1,89 @@
+package index
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/harness/helm-optimize/pkg/common"
+)
+
+// FileName is the name of a chart repository index
+const FileName = "index.yaml"
+
+// Options defines the parameters for optimizing a repository index
+type Options struct {
+	// Path is the index file or the repository directory holding index.yaml
+	Path string
+	// Output is the file the optimized index is written to (default: overwrite Path)
+	Output string
+	// KeepLast keeps the newest N versions of every chart; 0 keeps all
+	KeepLast int
+	// Keep keeps versions matching any of these semver ranges, e.g. ">=2.0.0 <3.0.0"
+	Keep []string
+	// DropDeprecated removes versions marked as deprecated
+	DropDeprecated bool
+	// RemoveOrphans deletes the archives of removed versions from the repository directory
+	RemoveOrphans bool
+	DryRun        bool
+	ShowDeleted   bool
+	Verbose       bool
+}
+
+// Run executes the index optimization with the given options
+func Run(opts Options) error {
+	path, err := resolvePath(opts.Path)
+	if err != nil {
+		return err
+	}
+	opts.Path = path
+	if opts.KeepLast < 0 {
+		return fmt.Errorf("--keep-last must not be negative")
+	}
+
+	fmt.Printf("Optimizing repository index '%s'...\n", opts.Path)
+
+	optimizer, err := NewOptimizer(opts)
+	if err != nil {
+		return err
+	}
+	return optimizer.Optimize()
+}
+
+// resolvePath returns the index file of an index path, which may be the
+// repository directory. The error wraps common.ErrChartNotFound when there is
+// no index.
+func resolvePath(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err == nil && info.IsDir() {
+		path = filepath.Join(path, FileName)
+		info, err = os.Stat(path)
+	}
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", fmt.Errorf("%w: index '%s' does not exist", common.ErrChartNotFound, path)
+		}
+		return "", err
+	}
+	return path, nil
+}
+
+// SameIndex reports whether output names the index file of path, which may
+// be the repository directory
+func SameIndex(path, output string) bool {
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		path = filepath.Join(path, FileName)
+	}
+	pathInfo, err := os.Stat(path)
+	if err == nil {
+		if outputInfo, err := os.Stat(output); err == nil {
+			return os.SameFile(pathInfo, outputInfo)
+		}
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	absOutput, err := filepath.Abs(output)
+	return err == nil && absPath == absOutput
+}
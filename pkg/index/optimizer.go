@@ -0,0 +1,241 @@
+package index
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/harness/helm-optimize/pkg/common"
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+// Reasons a chart version is removed from the index
+const (
+	ReasonDeprecated = "deprecated"
+	ReasonDuplicate  = "duplicate digest"
+	ReasonPolicy     = "outside retention policy"
+)
+
+// Optimizer prunes chart versions from a repository index
+type Optimizer struct {
+	opts   Options
+	ranges []*semver.Constraints
+	// removed holds the versions dropped from the index with the reason
+	removed []Removed
+	// orphans holds the archives no kept version refers to
+	orphans []string
+}
+
+// Removed describes a chart version dropped from the index
+type Removed struct {
+	Chart   string
+	Version string
+	Reason  string
+	URLs    []string
+}
+
+// NewOptimizer creates a new Optimizer, validating the keep ranges
+func NewOptimizer(opts Options) (*Optimizer, error) {
+	o := &Optimizer{opts: opts}
+	for _, r := range opts.Keep {
+		constraint, err := semver.NewConstraint(r)
+		if err != nil {
+			return nil, fmt.Errorf("invalid semver range '%s': %w", r, err)
+		}
+		o.ranges = append(o.ranges, constraint)
+	}
+	return o, nil
+}
+
+// Optimize prunes the index, writes it and removes orphaned archives when requested
+func (o *Optimizer) Optimize() error {
+	before, err := os.Stat(o.opts.Path)
+	if err != nil {
+		return err
+	}
+
+	// Entries are sorted newest first when loaded
+	index, err := repo.LoadIndexFile(o.opts.Path)
+	if err != nil {
+		return fmt.Errorf("failed to load index: %w", err)
+	}
+
+	var names []string
+	for name := range index.Entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	versionsBefore := 0
+	for _, name := range names {
+		versionsBefore += len(index.Entries[name])
+		index.Entries[name] = o.prune(name, index.Entries[name])
+		if len(index.Entries[name]) == 0 {
+			delete(index.Entries, name)
+		}
+	}
+
+	o.findOrphans(index)
+	o.printReport()
+
+	if o.opts.DryRun {
+		fmt.Printf("Dry run completed. %d of %d chart versions would be removed.\n", len(o.removed), versionsBefore)
+		return nil
+	}
+
+	output := o.opts.Output
+	if output == "" {
+		output = o.opts.Path
+	}
+	if err := index.WriteFile(output, before.Mode().Perm()); err != nil {
+		return fmt.Errorf("failed to write index: %w", err)
+	}
+
+	if o.opts.RemoveOrphans {
+		for _, path := range o.orphans {
+			if o.opts.Verbose || o.opts.ShowDeleted {
+				fmt.Printf("Removing orphaned archive: %s\n", path)
+			}
+			if err := common.Confine(filepath.Dir(o.opts.Path), path); err != nil {
+				return err
+			}
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to remove %s: %w", path, err)
+			}
+		}
+	}
+
+	after, err := os.Stat(output)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Index optimization completed. Removed %d of %d chart versions; index size %d -> %d bytes.\n",
+		len(o.removed), versionsBefore, before.Size(), after.Size())
+	return nil
+}
+
+// prune applies the retention policy to the versions of one chart, newest first
+func (o *Optimizer) prune(name string, versions repo.ChartVersions) repo.ChartVersions {
+	var kept repo.ChartVersions
+	digests := map[string]bool{}
+	retained := 0
+
+	for _, cv := range versions {
+		reason := ""
+		switch {
+		case o.opts.DropDeprecated && cv.Deprecated:
+			reason = ReasonDeprecated
+		case cv.Digest != "" && digests[cv.Digest]:
+			reason = ReasonDuplicate
+		case !o.retain(cv, retained):
+			reason = ReasonPolicy
+		}
+
+		if reason != "" {
+			o.removed = append(o.removed, Removed{Chart: name, Version: cv.Version, Reason: reason, URLs: cv.URLs})
+			continue
+		}
+
+		if cv.Digest != "" {
+			digests[cv.Digest] = true
+		}
+		retained++
+		kept = append(kept, cv)
+	}
+	return kept
+}
+
+// retain reports whether a version is kept by the policy, given how many
+// newer versions of the chart are already kept. Without a policy every
+// version is kept.
+func (o *Optimizer) retain(cv *repo.ChartVersion, retained int) bool {
+	if o.opts.KeepLast == 0 && len(o.ranges) == 0 {
+		return true
+	}
+	if o.opts.KeepLast > 0 && retained < o.opts.KeepLast {
+		return true
+	}
+
+	version, err := semver.NewVersion(cv.Version)
+	if err != nil {
+		return false
+	}
+	for _, r := range o.ranges {
+		if r.Check(version) {
+			return true
+		}
+	}
+	return false
+}
+
+// findOrphans lists the local archives of removed versions that no kept
+// version refers to. Only relative URLs and files inside the repository
+// directory are considered.
+func (o *Optimizer) findOrphans(index *repo.IndexFile) {
+	dir := filepath.Dir(o.opts.Path)
+
+	referenced := map[string]bool{}
+	for _, versions := range index.Entries {
+		for _, cv := range versions {
+			for _, u := range cv.URLs {
+				if path := localArchive(dir, u); path != "" {
+					referenced[path] = true
+				}
+			}
+		}
+	}
+
+	seen := map[string]bool{}
+	for _, r := range o.removed {
+		for _, u := range r.URLs {
+			path := localArchive(dir, u)
+			if path == "" || referenced[path] || seen[path] {
+				continue
+			}
+			seen[path] = true
+			if _, err := os.Stat(path); err == nil {
+				o.orphans = append(o.orphans, path)
+			}
+		}
+	}
+}
+
+// localArchive resolves a chart URL to a file in the repository directory, or
+// returns an empty string for remote URLs and paths escaping the directory
+func localArchive(dir, chartURL string) string {
+	u, err := url.Parse(chartURL)
+	if err != nil || u.Scheme != "" || u.Host != "" {
+		return ""
+	}
+	path := filepath.Join(dir, filepath.FromSlash(u.Path))
+	rel, err := filepath.Rel(dir, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return ""
+	}
+	return path
+}
+
+// printReport lists the removed versions and orphaned archives
+func (o *Optimizer) printReport() {
+	for _, r := range o.removed {
+		fmt.Printf("Removed %s %s (%s)\n", r.Chart, r.Version, r.Reason)
+	}
+	if len(o.orphans) == 0 {
+		return
+	}
+	switch {
+	case o.opts.RemoveOrphans && o.opts.DryRun:
+		fmt.Println("Orphaned archives (would be removed):")
+	case o.opts.RemoveOrphans:
+		fmt.Println("Orphaned archives (removed):")
+	default:
+		fmt.Println("Orphaned archives (kept, use --remove-orphans to delete):")
+	}
+	for _, path := range o.orphans {
+		fmt.Printf("  %s\n", path)
+	}
+}
@@ -0,0 +1,160 @@
+package lint
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/harness/helm-optimize/pkg/common"
+	helmlint "helm.sh/helm/v3/pkg/lint"
+	"helm.sh/helm/v3/pkg/lint/support"
+)
+
+// Severities of the lint findings compared before and after an optimization
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Options configures the lint runs around an optimization
+type Options struct {
+	// Strict fails on introduced warnings too, like 'helm lint --strict'
+	Strict bool
+	// ValueFiles are applied on top of the chart values, like 'helm lint -f'
+	ValueFiles []string
+}
+
+// Message is a single lint finding
+type Message struct {
+	Severity string `json:"severity"`
+	// Path is the chart file the finding refers to
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// String formats the finding the way 'helm lint' prints it
+func (m Message) String() string {
+	return fmt.Sprintf("[%s] %s: %s", m.Severity, m.Path, m.Message)
+}
+
+// Result holds the errors and warnings Helm's linter reported for a chart
+type Result struct {
+	Errors   []Message `json:"errors,omitempty"`
+	Warnings []Message `json:"warnings,omitempty"`
+}
+
+// Delta lists the findings introduced by an optimization
+type Delta struct {
+	Errors   []Message `json:"errors,omitempty"`
+	Warnings []Message `json:"warnings,omitempty"`
+	// Strict records whether introduced warnings fail the optimization
+	Strict bool `json:"strict,omitempty"`
+}
+
+// Failed reports whether the introduced findings fail the optimization
+func (d *Delta) Failed() bool {
+	return len(d.Errors) > 0 || (d.Strict && len(d.Warnings) > 0)
+}
+
+// Print lists the introduced findings
+func (d *Delta) Print(w io.Writer) {
+	if len(d.Errors) == 0 && len(d.Warnings) == 0 {
+		fmt.Fprintln(w, "Lint: no new errors or warnings.")
+		return
+	}
+	if len(d.Errors) > 0 {
+		fmt.Fprintln(w, "Lint errors introduced by the optimization:")
+		for _, m := range d.Errors {
+			fmt.Fprintf(w, "  %s\n", m)
+		}
+	}
+	if len(d.Warnings) > 0 {
+		fmt.Fprintln(w, "Lint warnings introduced by the optimization:")
+		for _, m := range d.Warnings {
+			fmt.Fprintf(w, "  %s\n", m)
+		}
+	}
+}
+
+// Lint runs Helm's linter against a chart directory
+func Lint(chartPath string, opts Options) (*Result, error) {
+	if _, err := os.Stat(filepath.Join(chartPath, "Chart.yaml")); err != nil {
+		return nil, fmt.Errorf("%w: no Chart.yaml in '%s'", common.ErrChartNotFound, chartPath)
+	}
+	vals, err := common.LoadValueFiles(opts.ValueFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	linter := helmlint.All(chartPath, vals, "", false)
+
+	result := &Result{}
+	for _, msg := range linter.Messages {
+		m := Message{Path: msg.Path, Message: msg.Err.Error()}
+		switch msg.Severity {
+		case support.ErrorSev:
+			m.Severity = SeverityError
+			result.Errors = append(result.Errors, m)
+		case support.WarningSev:
+			m.Severity = SeverityWarning
+			result.Warnings = append(result.Warnings, m)
+		}
+	}
+	return result, nil
+}
+
+// Guard lints a chart before an optimization and reports what the
+// optimization changed when the chart is linted again
+type Guard struct {
+	chartPath string
+	opts      Options
+	before    *Result
+}
+
+// Begin lints the chart before it is optimized
+func Begin(chartPath string, opts Options) (*Guard, error) {
+	before, err := Lint(chartPath, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lint chart before optimization: %w", err)
+	}
+	return &Guard{chartPath: chartPath, opts: opts, before: before}, nil
+}
+
+// End lints the optimized chart and returns the findings that were not
+// reported before. The error wraps common.ErrVerificationFailed when the
+// optimization introduced errors, or warnings in strict mode.
+func (g *Guard) End() (*Delta, error) {
+	after, err := Lint(g.chartPath, g.opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lint optimized chart: %w", err)
+	}
+
+	delta := &Delta{
+		Errors:   introduced(g.before.Errors, after.Errors),
+		Warnings: introduced(g.before.Warnings, after.Warnings),
+		Strict:   g.opts.Strict,
+	}
+	if delta.Failed() {
+		return delta, fmt.Errorf("%w: optimization introduced %d lint errors and %d lint warnings", common.ErrVerificationFailed, len(delta.Errors), len(delta.Warnings))
+	}
+	return delta, nil
+}
+
+// introduced returns the findings of after that are not in before, counting
+// repeated findings so that a second identical message is still reported
+func introduced(before, after []Message) []Message {
+	seen := map[Message]int{}
+	for _, m := range before {
+		seen[m]++
+	}
+	var added []Message
+	for _, m := range after {
+		if seen[m] > 0 {
+			seen[m]--
+			continue
+		}
+		added = append(added, m)
+	}
+	return added
+}
@@ -0,0 +1,38 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// Log formats
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// KnownFormats lists the supported log formats
+var KnownFormats = []string{FormatText, FormatJSON}
+
+// KnownLevels lists the supported log levels
+var KnownLevels = []string{"debug", "info", "warn", "error"}
+
+// New creates a logger writing records of at least the given level to w
+func New(w io.Writer, level, format string) (*slog.Logger, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("unknown log level '%s' (known: %s)", level, strings.Join(KnownLevels, ", "))
+	}
+
+	opts := &slog.HandlerOptions{Level: lvl}
+	switch format {
+	case FormatText:
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	case FormatJSON:
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	default:
+		return nil, fmt.Errorf("unknown log format '%s' (known: %s)", format, strings.Join(KnownFormats, ", "))
+	}
+}
@@ -0,0 +1,286 @@
+package prune
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/harness/helm-optimize/pkg/common"
+	"gopkg.in/yaml.v3"
+	"helm.sh/helm/v3/pkg/chart"
+	sigsyaml "sigs.k8s.io/yaml"
+)
+
+// dependency holds the Chart.yaml dependency fields relevant for pruning
+type dependency struct {
+	Name      string   `yaml:"name"`
+	Version   string   `yaml:"version"`
+	Alias     string   `yaml:"alias"`
+	Condition string   `yaml:"condition"`
+	Tags      []string `yaml:"tags"`
+}
+
+// readDependencies reads the dependencies declared in a chart's Chart.yaml
+func readDependencies(dir string) ([]dependency, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "Chart.yaml"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Chart.yaml: %w", err)
+	}
+
+	var meta struct {
+		Dependencies []dependency `yaml:"dependencies"`
+	}
+	if err := yaml.Unmarshal(data, &meta); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", filepath.Join(dir, "Chart.yaml"), err)
+	}
+	return meta.Dependencies, nil
+}
+
+// findSubchart returns the directory or archive holding a dependency in the
+// chart's charts/ directory, or an empty string when it is not vendored.
+// Archives are matched by their "<name>-<version>.tgz" file name.
+func findSubchart(dir, name string) string {
+	chartsDir := filepath.Join(dir, "charts")
+	candidate := filepath.Join(chartsDir, name)
+	if meta := readName(candidate); meta == name {
+		return candidate
+	}
+
+	entries, err := os.ReadDir(chartsDir)
+	if err != nil {
+		return ""
+	}
+	for _, entry := range entries {
+		path := filepath.Join(chartsDir, entry.Name())
+		if entry.IsDir() {
+			if readName(path) == name {
+				return path
+			}
+			continue
+		}
+		version := strings.TrimPrefix(entry.Name(), name+"-")
+		if version != entry.Name() && strings.HasSuffix(version, ".tgz") && version[0] >= '0' && version[0] <= '9' {
+			return path
+		}
+	}
+	return ""
+}
+
+// readName returns the chart name from a directory's Chart.yaml
+func readName(dir string) string {
+	data, err := os.ReadFile(filepath.Join(dir, "Chart.yaml"))
+	if err != nil {
+		return ""
+	}
+	var meta struct {
+		Name string `yaml:"name"`
+	}
+	if err := yaml.Unmarshal(data, &meta); err != nil {
+		return ""
+	}
+	return meta.Name
+}
+
+// chartEdit holds the new Chart.yaml and Chart.lock of a chart whose
+// dependencies are removed; nil content leaves a file unchanged
+type chartEdit struct {
+	dir   string
+	chart []byte
+	lock  []byte
+	// lockUnchanged is true when Chart.lock is kept as is because its digest cannot be recomputed
+	lockUnchanged bool
+}
+
+// planDependencyRemoval drops every entry for the named dependencies from the
+// chart's Chart.yaml and Chart.lock, preserving the rest of the files. Helm
+// refuses to install a chart whose Chart.yaml lists missing subcharts, and to
+// build one whose Chart.lock digest does not match, so the digest is
+// recomputed the way Helm's resolver does. A lock whose current digest cannot
+// be reproduced, e.g. one already out of sync, is left unchanged.
+func planDependencyRemoval(dir string, names map[string]bool) (*chartEdit, error) {
+	edit := &chartEdit{dir: dir}
+
+	chartFile := filepath.Join(dir, "Chart.yaml")
+	chartData, err := os.ReadFile(chartFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Chart.yaml: %w", err)
+	}
+	newChart, changed, err := dropDependencies(chartFile, chartData, names)
+	if err != nil || !changed {
+		return edit, err
+	}
+	edit.chart = newChart
+
+	lockFile := filepath.Join(dir, "Chart.lock")
+	lockData, err := os.ReadFile(lockFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return edit, nil
+		}
+		return nil, err
+	}
+
+	digest, recorded, err := lockDigest(lockFile, chartData, lockData)
+	if err != nil {
+		return nil, err
+	}
+	if digest != recorded {
+		edit.lockUnchanged = true
+		return edit, nil
+	}
+
+	newLock, _, err := dropDependencies(lockFile, lockData, names)
+	if err != nil {
+		return nil, err
+	}
+	digest, _, err = lockDigest(lockFile, newChart, newLock)
+	if err != nil {
+		return nil, err
+	}
+	edit.lock, err = setDigest(lockFile, newLock, digest)
+	if err != nil {
+		return nil, err
+	}
+	return edit, nil
+}
+
+// write stores the edited files. Nothing outside the chart root is written,
+// including through a symbolic link.
+func (e *chartEdit) write(root string) error {
+	for _, f := range []struct {
+		name string
+		data []byte
+	}{{"Chart.yaml", e.chart}, {"Chart.lock", e.lock}} {
+		if f.data == nil {
+			continue
+		}
+		path := filepath.Join(e.dir, f.name)
+		if err := confineWrite(root, path); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, f.data, 0644); err != nil {
+			return fmt.Errorf("failed to update %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// confineWrite checks with common.Confine that path lies below root and, as a
+// write follows a symbolic link, that the file it resolves to does too
+func confineWrite(root, path string) error {
+	if err := common.Confine(root, path); err != nil {
+		return err
+	}
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return err
+	}
+	target, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return err
+	}
+	return common.Confine(realRoot, target)
+}
+
+// lockDigest returns the digest Helm's resolver computes for the dependencies
+// of a Chart.yaml and a Chart.lock, and the digest recorded in the lock
+func lockDigest(lockFile string, chartData, lockData []byte) (string, string, error) {
+	var metadata chart.Metadata
+	if err := sigsyaml.Unmarshal(chartData, &metadata); err != nil {
+		return "", "", fmt.Errorf("failed to parse Chart.yaml: %w", err)
+	}
+	var lock chart.Lock
+	if err := sigsyaml.Unmarshal(lockData, &lock); err != nil {
+		return "", "", fmt.Errorf("failed to parse %s: %w", lockFile, err)
+	}
+
+	data, err := json.Marshal([2][]*chart.Dependency{metadata.Dependencies, lock.Dependencies})
+	if err != nil {
+		return "", "", err
+	}
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:]), lock.Digest, nil
+}
+
+// dropDependencies removes the entries of the named dependencies from a
+// Chart.yaml or Chart.lock and reports whether anything changed
+func dropDependencies(path string, data []byte, names map[string]bool) ([]byte, bool, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, false, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if !dropDependency(&doc, names) {
+		return data, false, nil
+	}
+	out, err := encode(&doc)
+	return out, true, err
+}
+
+// setDigest replaces the digest of a Chart.lock
+func setDigest(path string, data []byte, digest string) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("failed to parse %s: not a mapping", path)
+	}
+	root := doc.Content[0]
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == "digest" {
+			root.Content[i+1].Value = digest
+		}
+	}
+	return encode(&doc)
+}
+
+// encode writes a YAML document with the indentation Helm uses
+func encode(doc *yaml.Node) ([]byte, error) {
+	var out bytes.Buffer
+	enc := yaml.NewEncoder(&out)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// dropDependency removes the named entries from the dependencies sequence
+// and reports whether anything changed
+func dropDependency(doc *yaml.Node, names map[string]bool) bool {
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return false
+	}
+	root := doc.Content[0]
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value != "dependencies" {
+			continue
+		}
+		seq := root.Content[i+1]
+		var kept []*yaml.Node
+		for _, item := range seq.Content {
+			if !names[dependencyName(item)] {
+				kept = append(kept, item)
+			}
+		}
+		changed := len(kept) != len(seq.Content)
+		seq.Content = kept
+		return changed
+	}
+	return false
+}
+
+// dependencyName returns the name field of a dependency mapping node
+func dependencyName(node *yaml.Node) string {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == "name" {
+			return node.Content[i+1].Value
+		}
+	}
+	return ""
+}
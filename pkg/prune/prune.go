@@ -0,0 +1,49 @@
+package prune
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/harness/helm-optimize/pkg/common"
+)
+
+// Options defines the parameters for pruning disabled subcharts
+type Options struct {
+	ChartPath string
+	// ValueSets lists the values sets to evaluate; each set is a list of
+	// values files merged in order. A subchart is pruned only when it is
+	// disabled in every set.
+	ValueSets   [][]string
+	DryRun      bool
+	ShowDeleted bool
+	Verbose     bool
+	// Check fails with common.ErrNotOptimized when subcharts would be pruned; implies DryRun
+	Check bool
+	// Exclude skips subchart paths matching these globs or chart names
+	Exclude []string
+	// NeverDelete protects subchart paths matching these globs or chart names
+	NeverDelete []string
+}
+
+// Run executes the prune operation with the given options
+func Run(opts Options) error {
+	if len(opts.ValueSets) == 0 {
+		return fmt.Errorf("at least one values file is required to evaluate conditions and tags")
+	}
+
+	if opts.Check {
+		opts.DryRun = true
+	}
+
+	fmt.Printf("Pruning disabled subcharts of chart at '%s'...\n", opts.ChartPath)
+
+	pruner := NewPruner(opts)
+	if err := pruner.Prune(); err != nil {
+		return err
+	}
+
+	if opts.Check {
+		return common.CheckChanges(os.Stdout, "prune-disabled", opts.ChartPath, pruner.Changes())
+	}
+	return nil
+}
@@ -0,0 +1,315 @@
+package prune
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/harness/helm-optimize/pkg/common"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+)
+
+// Pruner removes subcharts disabled by conditions and tags in every values set
+type Pruner struct {
+	opts     Options
+	selector common.Selector
+	// pruned holds the subcharts removed (or that would be removed)
+	pruned []PrunedChart
+	// protected holds disabled subcharts kept because they are protected
+	protected []common.ProtectedPath
+	// edits holds the Chart.yaml and Chart.lock changes of the parents of the pruned subcharts
+	edits []*chartEdit
+}
+
+// PrunedChart describes a subchart disabled in every values set
+type PrunedChart struct {
+	// Path is the subchart directory or archive in the parent's charts/ directory
+	Path string
+	// Parent is the chart whose Chart.yaml declares the dependency
+	Parent string
+	// Dependency is the dependency name as declared in the parent Chart.yaml
+	Dependency string
+	// Reasons maps each values set to the values keys that disabled the subchart
+	Reasons map[string][]string
+	// LockUnchanged is true when the parent's Chart.lock is left unchanged
+	// because its digest cannot be recomputed
+	LockUnchanged bool
+}
+
+// instance tracks the state of one on-disk subchart for a single values set
+type instance struct {
+	path       string
+	parent     string
+	dependency string
+	enabled    bool
+	reasons    []string
+}
+
+// NewPruner creates a new Pruner instance
+func NewPruner(opts Options) *Pruner {
+	return &Pruner{opts: opts}
+}
+
+// Prune evaluates every values set and removes the subcharts disabled in all of them
+func (p *Pruner) Prune() error {
+	chartPath, err := filepath.Abs(p.opts.ChartPath)
+	if err != nil {
+		return fmt.Errorf("failed to get absolute path: %w", err)
+	}
+	p.selector = common.Selector{
+		Root:        chartPath,
+		Exclude:     p.opts.Exclude,
+		NeverDelete: p.opts.NeverDelete,
+	}
+
+	// disabled counts in how many values sets each subchart was disabled
+	disabled := map[string]int{}
+	found := map[string]*PrunedChart{}
+
+	for _, set := range p.opts.ValueSets {
+		name := strings.Join(set, ",")
+		instances, err := p.evaluate(chartPath, set)
+		if err != nil {
+			return fmt.Errorf("failed to evaluate values %s: %w", name, err)
+		}
+		for _, inst := range instances {
+			if inst.enabled {
+				continue
+			}
+			disabled[inst.path]++
+			entry, ok := found[inst.path]
+			if !ok {
+				entry = &PrunedChart{Path: inst.path, Parent: inst.parent, Dependency: inst.dependency, Reasons: map[string][]string{}}
+				found[inst.path] = entry
+			}
+			entry.Reasons[name] = inst.reasons
+		}
+	}
+
+	var paths []string
+	for path, count := range disabled {
+		if count == len(p.opts.ValueSets) {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		if reason := p.selector.Protection(path); reason != "" {
+			p.protected = append(p.protected, common.ProtectedPath{Path: path, Reason: reason})
+			continue
+		}
+		p.pruned = append(p.pruned, *found[path])
+	}
+
+	if err := p.planEdits(); err != nil {
+		return err
+	}
+
+	p.printReport()
+
+	if p.opts.DryRun {
+		fmt.Printf("Dry run completed. %d disabled subcharts would be removed.\n", len(p.pruned))
+		return nil
+	}
+
+	for _, e := range p.edits {
+		if err := e.write(chartPath); err != nil {
+			return err
+		}
+	}
+	for _, pc := range p.pruned {
+		if p.opts.Verbose || p.opts.ShowDeleted {
+			fmt.Printf("Removing disabled subchart: %s\n", pc.Path)
+		}
+		if err := common.RemoveAll(chartPath, pc.Path); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", pc.Path, err)
+		}
+	}
+	fmt.Printf("Prune completed. Removed %d disabled subcharts.\n", len(p.pruned))
+
+	return nil
+}
+
+// Pruned returns the subcharts disabled in every values set
+func (p *Pruner) Pruned() []PrunedChart {
+	return p.pruned
+}
+
+// Changes returns the paths pruning deletes and rewrites, or would in dry-run mode
+func (p *Pruner) Changes() []common.Change {
+	var changes []common.Change
+	for _, pc := range p.pruned {
+		changes = append(changes, common.Change{Action: common.ActionDelete, Path: pc.Path})
+	}
+	for _, e := range p.edits {
+		if e.chart != nil {
+			changes = append(changes, common.Change{Action: common.ActionRewrite, Path: filepath.Join(e.dir, "Chart.yaml")})
+		}
+		if e.lock != nil {
+			changes = append(changes, common.Change{Action: common.ActionRewrite, Path: filepath.Join(e.dir, "Chart.lock")})
+		}
+	}
+	return changes
+}
+
+// planEdits computes the Chart.yaml and Chart.lock changes of every parent
+// chart, removing all its pruned dependencies at once
+func (p *Pruner) planEdits() error {
+	names := map[string]map[string]bool{}
+	var parents []string
+	for _, pc := range p.pruned {
+		if names[pc.Parent] == nil {
+			names[pc.Parent] = map[string]bool{}
+			parents = append(parents, pc.Parent)
+		}
+		names[pc.Parent][pc.Dependency] = true
+	}
+	sort.Strings(parents)
+
+	unchanged := map[string]bool{}
+	for _, parent := range parents {
+		edit, err := planDependencyRemoval(parent, names[parent])
+		if err != nil {
+			return err
+		}
+		unchanged[parent] = edit.lockUnchanged
+		p.edits = append(p.edits, edit)
+	}
+	for i := range p.pruned {
+		p.pruned[i].LockUnchanged = unchanged[p.pruned[i].Parent]
+	}
+	return nil
+}
+
+// printReport lists the pruned subcharts with the keys that disabled them
+func (p *Pruner) printReport() {
+	for _, pc := range p.pruned {
+		fmt.Printf("Disabled subchart %s:\n", pc.Path)
+		var sets []string
+		for set := range pc.Reasons {
+			sets = append(sets, set)
+		}
+		sort.Strings(sets)
+		for _, set := range sets {
+			fmt.Printf("  %s: %s\n", set, strings.Join(pc.Reasons[set], ", "))
+		}
+	}
+	for _, pp := range p.protected {
+		fmt.Printf("Disabled subchart %s is protected (%s), keeping\n", pp.Path, pp.Reason)
+	}
+	for _, e := range p.edits {
+		if e.lockUnchanged {
+			fmt.Printf("Chart.lock of %s is left unchanged: its digest does not match Chart.yaml, run 'helm dependency update'\n", e.dir)
+		}
+	}
+}
+
+// evaluate applies Helm's condition and tag semantics for one values set and
+// returns the state of every reachable on-disk subchart
+func (p *Pruner) evaluate(chartPath string, valueFiles []string) ([]instance, error) {
+	c, err := loader.Load(chartPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load chart: %w", err)
+	}
+
+	vals, err := common.LoadValueFiles(valueFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	// Coalesced values are used to explain which keys disabled a subchart
+	cvals, err := chartutil.CoalesceValues(c, vals)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := chartutil.ProcessDependencies(c, vals); err != nil {
+		return nil, fmt.Errorf("failed to process dependencies: %w", err)
+	}
+
+	var instances []instance
+	err = p.walk(c, chartPath, "", cvals, &instances)
+	return instances, err
+}
+
+// walk compares the dependencies declared on disk with the dependencies Helm
+// kept enabled, and recurses into enabled unpacked subcharts
+func (p *Pruner) walk(c *chart.Chart, dir, prefix string, cvals chartutil.Values, out *[]instance) error {
+	declared, err := readDependencies(dir)
+	if err != nil {
+		return err
+	}
+
+	enabled := map[string]*chart.Chart{}
+	for _, dep := range c.Dependencies() {
+		enabled[dep.Name()] = dep
+	}
+
+	// A subchart stays when any dependency entry referring to it is enabled
+	byPath := map[string]*instance{}
+	var order []string
+	for _, dep := range declared {
+		path := findSubchart(dir, dep.Name)
+		if path == "" || p.selector.Excluded(path) {
+			continue
+		}
+
+		inst, ok := byPath[path]
+		if !ok {
+			inst = &instance{path: path, parent: dir, dependency: dep.Name}
+			byPath[path] = inst
+			order = append(order, path)
+		}
+
+		name := dep.Name
+		if dep.Alias != "" {
+			name = dep.Alias
+		}
+		if sub, ok := enabled[name]; ok {
+			inst.enabled = true
+			if info, err := os.Stat(path); err == nil && info.IsDir() {
+				if err := p.walk(sub, path, prefix+name+".", cvals, out); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		inst.reasons = append(inst.reasons, disabledReason(dep, cvals, prefix))
+	}
+
+	for _, path := range order {
+		*out = append(*out, *byPath[path])
+	}
+	return nil
+}
+
+// disabledReason names the values key that disabled a dependency. Conditions
+// take precedence over tags, as in Helm.
+func disabledReason(dep dependency, cvals chartutil.Values, prefix string) string {
+	for _, cond := range strings.Split(strings.TrimSpace(dep.Condition), ",") {
+		cond = strings.TrimSpace(cond)
+		if cond == "" {
+			continue
+		}
+		if v, err := cvals.PathValue(prefix + cond); err == nil {
+			if b, ok := v.(bool); ok {
+				return fmt.Sprintf("condition %s%s=%t", prefix, cond, b)
+			}
+		}
+	}
+
+	if tags, err := cvals.Table("tags"); err == nil {
+		for _, tag := range dep.Tags {
+			if v, ok := tags[tag].(bool); ok && !v {
+				return fmt.Sprintf("tag tags.%s=false", tag)
+			}
+		}
+	}
+
+	return "disabled"
+}
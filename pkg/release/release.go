@@ -0,0 +1,143 @@
+package release
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/harness/helm-optimize/pkg/common"
+	"github.com/harness/helm-optimize/pkg/render"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	helmrelease "helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/releaseutil"
+	helmtime "helm.sh/helm/v3/pkg/time"
+)
+
+// StorageLimit is the maximum size of a Kubernetes Secret or ConfigMap, which
+// bounds the release record Helm stores for every revision
+const StorageLimit = 1024 * 1024
+
+// notesFile is the template whose output becomes the release notes
+const notesFile = "NOTES.txt"
+
+// Options defines the release the size is estimated for
+type Options struct {
+	ValueFiles  []string
+	ReleaseName string
+	Namespace   string
+}
+
+// Measure loads the chart at chartPath and returns the encoded size of its release record
+func Measure(chartPath string, opts Options) (int, error) {
+	c, err := loader.Load(chartPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load chart: %w", err)
+	}
+	return EncodedSize(c, opts)
+}
+
+// EncodedSize renders the chart and returns the size of the release record
+// Helm would store for it: the release JSON, gzipped and base64 encoded, as
+// done by Helm's Secret and ConfigMap storage drivers. The record is assembled
+// like 'helm install' does: hooks are split from the manifest and the notes of
+// the root chart are kept. It holds the files of the root chart only;
+// subcharts are not serialized.
+func EncodedSize(c *chart.Chart, opts Options) (int, error) {
+	rls, err := Build(c, opts)
+	if err != nil {
+		return 0, err
+	}
+	return encodedLen(rls)
+}
+
+// Build renders the chart and returns the release record of a first install
+func Build(c *chart.Chart, opts Options) (*helmrelease.Release, error) {
+	config, err := common.LoadValueFiles(opts.ValueFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	rendered, err := render.Raw(c, render.Options{
+		ValueFiles:  opts.ValueFiles,
+		ReleaseName: opts.ReleaseName,
+		Namespace:   opts.Namespace,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Only the notes of the root chart are kept, as without --render-subchart-notes
+	var notes string
+	for name, content := range rendered {
+		if strings.HasSuffix(name, notesFile) {
+			if name == path.Join(c.Name(), "templates", notesFile) {
+				notes = content
+			}
+			delete(rendered, name)
+		}
+	}
+
+	hooks, manifests, err := releaseutil.SortManifests(rendered, nil, releaseutil.InstallOrder)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sort manifests: %w", err)
+	}
+	var manifest strings.Builder
+	for _, m := range manifests {
+		fmt.Fprintf(&manifest, "---\n# Source: %s\n%s\n", m.Name, m.Content)
+	}
+
+	name := opts.ReleaseName
+	if name == "" {
+		name = render.DefaultReleaseName
+	}
+	namespace := opts.Namespace
+	if namespace == "" {
+		namespace = render.DefaultNamespace
+	}
+
+	now := helmtime.Time{Time: time.Now()}
+	return &helmrelease.Release{
+		Name:      name,
+		Namespace: namespace,
+		Version:   1,
+		Chart:     c,
+		Config:    config,
+		Manifest:  manifest.String(),
+		Hooks:     hooks,
+		Info: &helmrelease.Info{
+			FirstDeployed: now,
+			LastDeployed:  now,
+			Status:        helmrelease.StatusDeployed,
+			Description:   "Install complete",
+			Notes:         notes,
+		},
+	}, nil
+}
+
+// encodedLen returns the length of the release encoded like Helm's storage drivers
+func encodedLen(rls *helmrelease.Release) (int, error) {
+	data, err := json.Marshal(rls)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode release: %w", err)
+	}
+
+	var buf bytes.Buffer
+	w, err := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := w.Write(data); err != nil {
+		return 0, err
+	}
+	if err := w.Close(); err != nil {
+		return 0, err
+	}
+
+	return base64.StdEncoding.EncodedLen(buf.Len()), nil
+}
@@ -0,0 +1,198 @@
+package render
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/harness/helm-optimize/pkg/common"
+	"gopkg.in/yaml.v3"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/engine"
+)
+
+// Default release settings used when rendering without a cluster
+const (
+	DefaultReleaseName = "release-name"
+	DefaultNamespace   = "default"
+)
+
+// Options defines the parameters for rendering a chart
+type Options struct {
+	ChartPath   string
+	ValueFiles  []string
+	ReleaseName string
+	Namespace   string
+	// KubeVersion overrides the Kubernetes version reported in .Capabilities
+	KubeVersion string
+}
+
+// Manifest is a single rendered Kubernetes object
+type Manifest struct {
+	// Template is the template path, e.g. "umbrella/charts/redis/templates/svc.yaml"
+	Template string
+	// Subchart is the chain of subchart names producing the manifest, e.g. "a/redis";
+	// empty for the root chart
+	Subchart string
+	// Content is the YAML document
+	Content string
+	// Object is the parsed document
+	Object map[string]interface{}
+}
+
+// Kind returns the kind of the object
+func (m Manifest) Kind() string {
+	kind, _ := m.Object["kind"].(string)
+	return kind
+}
+
+// APIVersion returns the apiVersion of the object
+func (m Manifest) APIVersion() string {
+	apiVersion, _ := m.Object["apiVersion"].(string)
+	return apiVersion
+}
+
+// Name returns the metadata.name of the object
+func (m Manifest) Name() string {
+	metadata, _ := m.Object["metadata"].(map[string]interface{})
+	name, _ := metadata["name"].(string)
+	return name
+}
+
+// Load loads the chart and renders it the way 'helm template' does: disabled
+// dependencies are dropped and values are coalesced before rendering
+func Load(opts Options) (*chart.Chart, map[string]string, error) {
+	c, err := loader.Load(opts.ChartPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: failed to load chart: %w", common.ErrInvalidChart, err)
+	}
+
+	rendered, err := Chart(c, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	return c, rendered, nil
+}
+
+// Chart renders a loaded chart and returns the output keyed by template path.
+// Partials, NOTES.txt and empty output are omitted.
+func Chart(c *chart.Chart, opts Options) (map[string]string, error) {
+	rendered, err := Raw(c, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	out := map[string]string{}
+	for name, content := range rendered {
+		base := path.Base(name)
+		if strings.HasPrefix(base, "_") || base == "NOTES.txt" || strings.TrimSpace(content) == "" {
+			continue
+		}
+		out[name] = content
+	}
+	return out, nil
+}
+
+// Raw renders a loaded chart and returns the unfiltered engine output keyed by
+// template path, as Helm's install action receives it
+func Raw(c *chart.Chart, opts Options) (map[string]string, error) {
+	vals, err := common.LoadValueFiles(opts.ValueFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := chartutil.ProcessDependenciesWithMerge(c, vals); err != nil {
+		return nil, fmt.Errorf("failed to process dependencies: %w", err)
+	}
+
+	releaseName := opts.ReleaseName
+	if releaseName == "" {
+		releaseName = DefaultReleaseName
+	}
+	namespace := opts.Namespace
+	if namespace == "" {
+		namespace = DefaultNamespace
+	}
+
+	caps := chartutil.DefaultCapabilities.Copy()
+	if opts.KubeVersion != "" {
+		kubeVersion, err := chartutil.ParseKubeVersion(opts.KubeVersion)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Kubernetes version: %w", err)
+		}
+		caps.KubeVersion = *kubeVersion
+	}
+
+	values, err := chartutil.ToRenderValues(c, vals, chartutil.ReleaseOptions{
+		Name:      releaseName,
+		Namespace: namespace,
+		Revision:  1,
+		IsInstall: true,
+	}, caps)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute values: %w", err)
+	}
+
+	rendered, err := engine.Render(c, values)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render chart: %w", err)
+	}
+	return rendered, nil
+}
+
+// documentSeparator splits multi-document YAML output
+var documentSeparator = regexp.MustCompile(`(?m)^---[ \t]*(#.*)?$`)
+
+// Split parses rendered templates into individual manifests, ordered by
+// template path. Documents that are empty or not YAML objects are skipped.
+func Split(rendered map[string]string) ([]Manifest, error) {
+	var names []string
+	for name := range rendered {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var manifests []Manifest
+	for _, name := range names {
+		for _, doc := range documentSeparator.Split(rendered[name], -1) {
+			if strings.TrimSpace(doc) == "" {
+				continue
+			}
+			var obj map[string]interface{}
+			if err := yaml.Unmarshal([]byte(doc), &obj); err != nil {
+				return nil, fmt.Errorf("failed to parse output of %s: %w", name, err)
+			}
+			if len(obj) == 0 {
+				continue
+			}
+			manifests = append(manifests, Manifest{
+				Template: name,
+				Subchart: SubchartOf(name),
+				Content:  strings.TrimSpace(doc) + "\n",
+				Object:   obj,
+			})
+		}
+	}
+	return manifests, nil
+}
+
+// SubchartOf returns the subchart chain of a template path:
+// "umbrella/charts/a/charts/redis/templates/x.yaml" yields "a/redis"
+func SubchartOf(template string) string {
+	parts := strings.Split(template, "/")
+	var chain []string
+	for i := 0; i+1 < len(parts); i++ {
+		if parts[i] == "charts" {
+			chain = append(chain, parts[i+1])
+			i++
+		}
+		if parts[i] == "templates" || parts[i] == "crds" {
+			break
+		}
+	}
+	return strings.Join(chain, "/")
+}
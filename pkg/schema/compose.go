@@ -0,0 +1,320 @@
+package schema
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/harness/helm-optimize/pkg/common"
+	"helm.sh/helm/v3/pkg/chart"
+)
+
+// Draft is the JSON Schema version declared by the composed schema
+const Draft = "http://json-schema.org/draft-07/schema#"
+
+// Scope is a chart of the tree and the values key its values live under
+type Scope struct {
+	// Path is the chain of values keys from the root, e.g. "a.cache"; empty for the root chart
+	Path  string `json:"path"`
+	Chart string `json:"chart"`
+	// Definition is the $defs entry holding the chart schema, when it is shared
+	Definition string `json:"definition,omitempty"`
+	// HasSchema is false for charts without a values.schema.json
+	HasSchema bool `json:"hasSchema"`
+}
+
+// unit is the values.schema.json of one chart version, shared by every scope
+// using an identical schema
+type unit struct {
+	name   string
+	schema map[string]interface{}
+	uses   int
+	// recursive is true when the schema refers to itself with "#"
+	recursive bool
+}
+
+// composer builds the umbrella schema of a chart tree
+type composer struct {
+	// units holds the chart schemas keyed by the digest of their content
+	units map[string]*unit
+	// names holds the $defs names taken by units
+	names map[string]bool
+	defs  map[string]interface{}
+	// globals holds the distinct schemas of the global values
+	globals       []interface{}
+	globalDigests map[string]bool
+	scopes        []Scope
+}
+
+// Compose builds a single JSON Schema for the values of the umbrella chart.
+// Every subchart schema is nested under the values key of the subchart, its
+// alias or name; identical schemas are stored once under $defs, and the
+// constraints subcharts place on global values apply to the umbrella's global.
+func Compose(c *chart.Chart) (map[string]interface{}, []Scope, error) {
+	cp := &composer{
+		units:         map[string]*unit{},
+		names:         map[string]bool{},
+		defs:          map[string]interface{}{},
+		globalDigests: map[string]bool{},
+	}
+
+	// Count the uses of every schema first, so that only shared ones move to $defs
+	if err := cp.collect(c); err != nil {
+		return nil, nil, err
+	}
+	root, err := cp.compose(c, "", true)
+	if err != nil {
+		return nil, nil, err
+	}
+	if root == nil {
+		root = map[string]interface{}{"type": "object"}
+	}
+
+	if len(cp.globals) > 0 {
+		var global interface{} = cp.globals[0]
+		if len(cp.globals) > 1 {
+			global = map[string]interface{}{"allOf": cp.globals}
+		}
+		root = map[string]interface{}{
+			"allOf": []interface{}{
+				root,
+				map[string]interface{}{"properties": map[string]interface{}{"global": global}},
+			},
+		}
+	}
+
+	doc := map[string]interface{}{"$schema": Draft}
+	for key, value := range root {
+		doc[key] = value
+	}
+	if len(cp.defs) > 0 {
+		doc["$defs"] = cp.defs
+	}
+	return doc, cp.scopes, nil
+}
+
+// collect registers the schema of every chart of the tree
+func (cp *composer) collect(c *chart.Chart) error {
+	if len(c.Schema) > 0 {
+		u, err := cp.unit(c)
+		if err != nil {
+			return err
+		}
+		u.uses++
+	}
+	// A subchart used under several aliases counts once per alias
+	for _, key := range valuesKeys(c) {
+		if sub := subchart(c, key.name); sub != nil {
+			if err := cp.collect(sub); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// unit returns the shared unit of a chart schema
+func (cp *composer) unit(c *chart.Chart) (*unit, error) {
+	var schema map[string]interface{}
+	if err := json.Unmarshal(c.Schema, &schema); err != nil {
+		return nil, fmt.Errorf("%w: invalid values.schema.json in chart %s: %w", common.ErrInvalidChart, c.Name(), err)
+	}
+	canonical, err := json.Marshal(schema)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(canonical)
+	digest := hex.EncodeToString(sum[:])
+	if u, ok := cp.units[digest]; ok {
+		return u, nil
+	}
+
+	name := c.Name() + "-" + c.Metadata.Version
+	for i := 2; cp.names[name]; i++ {
+		name = fmt.Sprintf("%s-%s-%d", c.Name(), c.Metadata.Version, i)
+	}
+	cp.names[name] = true
+
+	u := &unit{name: name}
+	u.schema, u.recursive = cp.hoist(schema, name)
+	cp.units[digest] = u
+	return u, nil
+}
+
+// compose returns the schema of a chart's values, with its subcharts nested
+// under their values keys; nil when no chart of the subtree has a schema
+func (cp *composer) compose(c *chart.Chart, path string, root bool) (map[string]interface{}, error) {
+	scope := Scope{Path: path, Chart: c.Name() + "-" + c.Metadata.Version, HasSchema: len(c.Schema) > 0}
+
+	var own map[string]interface{}
+	if len(c.Schema) > 0 {
+		u, err := cp.unit(c)
+		if err != nil {
+			return nil, err
+		}
+		cp.addGlobal(u.schema)
+		// The root schema is the document itself and is never shared, but a
+		// recursive one still needs its definition
+		shared := !root && (u.uses > 1 || u.recursive)
+		if shared || u.recursive {
+			cp.defs[u.name] = u.schema
+		}
+		if shared {
+			own = map[string]interface{}{"$ref": "#/$defs/" + u.name}
+			scope.Definition = u.name
+		} else {
+			own = copySchema(u.schema)
+		}
+	}
+	cp.scopes = append(cp.scopes, scope)
+
+	children := map[string]interface{}{}
+	for _, key := range valuesKeys(c) {
+		sub := subchart(c, key.name)
+		if sub == nil {
+			continue
+		}
+		child, err := cp.compose(sub, joinPath(path, key.scope), false)
+		if err != nil {
+			return nil, err
+		}
+		if child != nil {
+			children[key.scope] = child
+		}
+	}
+
+	switch {
+	case len(children) == 0:
+		return own, nil
+	case own == nil:
+		return map[string]interface{}{"type": "object", "properties": children}, nil
+	default:
+		return map[string]interface{}{
+			"allOf": []interface{}{own, map[string]interface{}{"properties": children}},
+		}, nil
+	}
+}
+
+// addGlobal records the constraints a chart schema places on global values
+func (cp *composer) addGlobal(schema map[string]interface{}) {
+	props, _ := schema["properties"].(map[string]interface{})
+	global, ok := props["global"]
+	if !ok {
+		return
+	}
+	canonical, _ := json.Marshal(global)
+	sum := sha256.Sum256(canonical)
+	digest := hex.EncodeToString(sum[:])
+	if cp.globalDigests[digest] {
+		return
+	}
+	cp.globalDigests[digest] = true
+	cp.globals = append(cp.globals, global)
+}
+
+// hoist moves the definitions of a chart schema to the composed $defs, named
+// after the unit, and rewrites the local references to them
+func (cp *composer) hoist(schema map[string]interface{}, name string) (map[string]interface{}, bool) {
+	recursive := false
+	prefixes := map[string]string{}
+	for _, key := range []string{"definitions", "$defs"} {
+		defs, ok := schema[key].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for defName, def := range defs {
+			cp.defs[name+"."+defName] = def
+		}
+		prefixes["#/"+key+"/"] = "#/$defs/" + name + "."
+		delete(schema, key)
+	}
+	delete(schema, "$schema")
+	delete(schema, "$id")
+
+	var rewrite func(v interface{})
+	rewrite = func(v interface{}) {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			if ref, ok := v["$ref"].(string); ok {
+				if ref == "#" {
+					v["$ref"] = "#/$defs/" + name
+					recursive = true
+				}
+				for from, to := range prefixes {
+					if strings.HasPrefix(ref, from) {
+						v["$ref"] = to + strings.TrimPrefix(ref, from)
+					}
+				}
+			}
+			for _, value := range v {
+				rewrite(value)
+			}
+		case []interface{}:
+			for _, item := range v {
+				rewrite(item)
+			}
+		}
+	}
+	rewrite(schema)
+	for defName := range cp.defs {
+		if strings.HasPrefix(defName, name+".") {
+			rewrite(cp.defs[defName])
+		}
+	}
+	return schema, recursive
+}
+
+// valuesKey is a subchart and the values key its values live under
+type valuesKey struct {
+	name  string
+	scope string
+}
+
+// valuesKeys lists the subcharts of a chart: the declared dependencies under
+// their alias or name, then the undeclared charts under their name
+func valuesKeys(c *chart.Chart) []valuesKey {
+	var keys []valuesKey
+	declared := map[string]bool{}
+	for _, dep := range c.Metadata.Dependencies {
+		scope := dep.Name
+		if dep.Alias != "" {
+			scope = dep.Alias
+		}
+		keys = append(keys, valuesKey{name: dep.Name, scope: scope})
+		declared[dep.Name] = true
+	}
+	for _, sub := range c.Dependencies() {
+		if !declared[sub.Name()] {
+			keys = append(keys, valuesKey{name: sub.Name(), scope: sub.Name()})
+		}
+	}
+	return keys
+}
+
+// subchart returns the loaded subchart with the given name
+func subchart(c *chart.Chart, name string) *chart.Chart {
+	for _, sub := range c.Dependencies() {
+		if sub.Name() == name {
+			return sub
+		}
+	}
+	return nil
+}
+
+// joinPath appends a values key to a dotted path
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// copySchema deep-copies a schema so that inlined copies stay independent
+func copySchema(v map[string]interface{}) map[string]interface{} {
+	data, _ := json.Marshal(v)
+	var out map[string]interface{}
+	_ = json.Unmarshal(data, &out)
+	return out
+}
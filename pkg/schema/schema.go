@@ -0,0 +1,140 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/harness/helm-optimize/pkg/common"
+	"github.com/xeipuuv/gojsonschema"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+)
+
+// Options defines the parameters for composing and validating the umbrella schema
+type Options struct {
+	ChartPath string
+	// Output receives the composed schema; stdout when empty and no values are validated
+	Output string
+	// ValueFiles are validated against the composed schema, on top of the chart defaults
+	ValueFiles []string
+	Verbose    bool
+}
+
+// Run composes the umbrella schema of the chart, writes it and validates the
+// values files. The error wraps common.ErrVerificationFailed when the values
+// are invalid.
+func Run(opts Options) error {
+	c, err := loader.Load(opts.ChartPath)
+	if err != nil {
+		return fmt.Errorf("%w: failed to load chart: %w", common.ErrInvalidChart, err)
+	}
+
+	doc, scopes, err := Compose(c)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	switch {
+	case opts.Output != "":
+		if err := os.WriteFile(opts.Output, data, 0o644); err != nil {
+			return fmt.Errorf("failed to write schema: %w", err)
+		}
+		fmt.Printf("Schema written to: %s\n", opts.Output)
+	case len(opts.ValueFiles) == 0:
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+
+	if opts.Verbose {
+		fmt.Println("Values scopes:")
+		for _, s := range scopes {
+			switch {
+			case s.Definition != "":
+				fmt.Printf("  %s (%s): $defs/%s\n", scopeLabel(s.Path), s.Chart, s.Definition)
+			case s.HasSchema:
+				fmt.Printf("  %s (%s): inlined\n", scopeLabel(s.Path), s.Chart)
+			default:
+				fmt.Printf("  %s (%s): no schema\n", scopeLabel(s.Path), s.Chart)
+			}
+		}
+	}
+
+	if len(opts.ValueFiles) == 0 {
+		return nil
+	}
+
+	errs, err := Validate(c, opts.ValueFiles)
+	if err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		fmt.Println("Values do not match the schema:")
+		for _, e := range errs {
+			fmt.Printf("  %s\n", e)
+		}
+		return fmt.Errorf("%w: %d schema violations in the values", common.ErrVerificationFailed, len(errs))
+	}
+	fmt.Println("Values match the schema.")
+	return nil
+}
+
+// Validate checks the values files, coalesced with the defaults of the chart
+// tree the way Helm does before rendering, and returns the violations. Like
+// helm install, it only enforces the schemas of the subcharts left enabled by
+// their conditions and tags: the schema is composed from the chart tree after
+// the dependencies are processed, which removes the disabled subcharts from c.
+func Validate(c *chart.Chart, valueFiles []string) ([]string, error) {
+	vals, err := common.LoadValueFiles(valueFiles)
+	if err != nil {
+		return nil, err
+	}
+	if err := chartutil.ProcessDependenciesWithMerge(c, vals); err != nil {
+		return nil, fmt.Errorf("failed to process dependencies: %w", err)
+	}
+	coalesced, err := chartutil.CoalesceValues(c, vals)
+	if err != nil {
+		return nil, fmt.Errorf("failed to coalesce values: %w", err)
+	}
+
+	doc, _, err := Compose(c)
+	if err != nil {
+		return nil, err
+	}
+
+	schema, err := gojsonschema.NewSchema(gojsonschema.NewGoLoader(doc))
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile the composed schema: %w", err)
+	}
+	result, err := schema.Validate(gojsonschema.NewGoLoader(coalesced.AsMap()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to validate values: %w", err)
+	}
+
+	var errs []string
+	for _, e := range result.Errors() {
+		// allOf reports every failing branch again; the branch errors suffice
+		if e.Type() == "number_all_of" {
+			continue
+		}
+		errs = append(errs, fmt.Sprintf("%s: %s", e.Field(), e.Description()))
+	}
+	sort.Strings(errs)
+	return errs, nil
+}
+
+// scopeLabel names a values scope in the output
+func scopeLabel(path string) string {
+	if path == "" {
+		return "(root)"
+	}
+	return path
+}
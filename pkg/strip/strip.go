@@ -0,0 +1,50 @@
+package strip
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/harness/helm-optimize/pkg/common"
+)
+
+// Options defines the parameters for stripping files from a chart tree
+type Options struct {
+	ChartPath   string
+	Patterns    []string
+	DryRun      bool
+	ShowDeleted bool
+	Verbose     bool
+	// Check fails with common.ErrNotOptimized when files would be stripped; implies DryRun
+	Check bool
+	// Include limits stripping to the files of subcharts matching these globs
+	Include []string
+	// Exclude skips subchart paths matching these globs
+	Exclude []string
+	// NeverDelete protects paths matching these globs from deletion
+	NeverDelete []string
+}
+
+// Run executes the strip operation with the given options
+func Run(opts Options) error {
+	if opts.Verbose {
+		fmt.Printf("Starting strip of chart at %s\n", opts.ChartPath)
+	}
+
+	if opts.Check {
+		opts.DryRun = true
+	}
+
+	stripper := NewStripper(opts)
+	if err := stripper.Strip(); err != nil {
+		return err
+	}
+
+	if opts.Check {
+		var changes []common.Change
+		for _, path := range stripper.DeletedPaths() {
+			changes = append(changes, common.Change{Action: common.ActionDelete, Path: path})
+		}
+		return common.CheckChanges(os.Stdout, "strip", opts.ChartPath, changes)
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package strip
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/harness/helm-optimize/pkg/common"
+)
+
+// Stripper removes files matching the configured patterns from every chart in a tree
+type Stripper struct {
+	opts         Options
+	deletedPaths []string
+	selector     common.Selector
+	// root is the absolute path of the chart being stripped; nothing outside it is removed
+	root string
+}
+
+// NewStripper creates a new Stripper instance
+func NewStripper(opts Options) *Stripper {
+	return &Stripper{
+		opts:         opts,
+		deletedPaths: []string{},
+	}
+}
+
+// DeletedPaths returns the files removed, or that would be removed in dry-run mode
+func (s *Stripper) DeletedPaths() []string {
+	return s.deletedPaths
+}
+
+// Strip walks the chart tree and removes matching files
+func (s *Stripper) Strip() error {
+	chartPath, err := filepath.Abs(s.opts.ChartPath)
+	if err != nil {
+		return fmt.Errorf("failed to get absolute path: %w", err)
+	}
+	s.root = chartPath
+	s.selector = common.Selector{
+		Root:        chartPath,
+		Exclude:     s.opts.Exclude,
+		NeverDelete: s.opts.NeverDelete,
+	}
+
+	if err := s.stripChart(chartPath); err != nil {
+		return err
+	}
+
+	if s.opts.ShowDeleted && len(s.deletedPaths) > 0 {
+		fmt.Println("Stripped files:")
+		for _, path := range s.deletedPaths {
+			fmt.Printf("  %s\n", path)
+		}
+	}
+
+	if s.opts.DryRun {
+		fmt.Printf("Dry run completed. %d files would be stripped.\n", len(s.deletedPaths))
+	} else {
+		fmt.Printf("Strip completed. Removed %d files.\n", len(s.deletedPaths))
+	}
+
+	return nil
+}
+
+// stripChart removes matching files of a single chart and recurses into its subcharts.
+// Patterns are matched against paths relative to the chart the file belongs to.
+func (s *Stripper) stripChart(chartPath string) error {
+	if s.opts.Verbose {
+		fmt.Printf("Processing chart: %s\n", chartPath)
+	}
+
+	// Charts outside the include patterns keep their files, but their subcharts may match
+	if len(s.opts.Include) > 0 && !common.MatchAny(s.opts.Include, s.selector.Rel(chartPath)) {
+		return s.stripSubcharts(chartPath)
+	}
+
+	chartsDir := filepath.Join(chartPath, "charts")
+	err := filepath.WalkDir(chartPath, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		// Subcharts are handled by their own stripChart call
+		if entry.IsDir() && path == chartsDir {
+			return filepath.SkipDir
+		}
+
+		rel, err := filepath.Rel(chartPath, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		// Chart.yaml is required for the chart to remain loadable
+		if rel == "Chart.yaml" || !common.MatchAny(s.opts.Patterns, rel) {
+			return nil
+		}
+		if !s.selector.CanDelete(path) {
+			if s.opts.Verbose {
+				fmt.Printf("Skipping %s - protected by configuration\n", path)
+			}
+			return nil
+		}
+
+		if s.opts.Verbose {
+			fmt.Printf("Stripping %s\n", path)
+		}
+		if !s.opts.DryRun {
+			if err := common.RemoveAll(s.root, path); err != nil {
+				return fmt.Errorf("failed to remove %s: %w", path, err)
+			}
+		}
+		s.deletedPaths = append(s.deletedPaths, path)
+
+		if entry.IsDir() {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.stripSubcharts(chartPath)
+}
+
+// stripSubcharts strips the unpacked subcharts of a chart
+func (s *Stripper) stripSubcharts(chartPath string) error {
+	chartsDir := filepath.Join(chartPath, "charts")
+	entries, err := os.ReadDir(chartsDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read charts directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		subchartPath := filepath.Join(chartsDir, entry.Name())
+		if s.selector.Excluded(subchartPath) {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(subchartPath, "Chart.yaml")); err != nil {
+			continue
+		}
+		if err := s.stripChart(subchartPath); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
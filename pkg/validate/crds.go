@@ -0,0 +1,112 @@
+package validate
+
+import (
+	"github.com/harness/helm-optimize/pkg/render"
+)
+
+// addCRDs registers the schemas of the CustomResourceDefinitions among the
+// manifests, so that the custom resources of the chart are validated too
+func (s *schemaSet) addCRDs(manifests []render.Manifest) {
+	for _, m := range manifests {
+		if m.APIVersion() != "apiextensions.k8s.io/v1" || m.Kind() != "CustomResourceDefinition" {
+			continue
+		}
+		spec, _ := m.Object["spec"].(map[string]interface{})
+		group, _ := spec["group"].(string)
+		names, _ := spec["names"].(map[string]interface{})
+		kind, _ := names["kind"].(string)
+		versions, _ := spec["versions"].([]interface{})
+		for _, v := range versions {
+			v, _ := v.(map[string]interface{})
+			name, _ := v["name"].(string)
+			schema, _ := v["schema"].(map[string]interface{})
+			openAPI, _ := schema["openAPIV3Schema"].(map[string]interface{})
+			if openAPI == nil {
+				openAPI = map[string]interface{}{"type": "object", "x-kubernetes-preserve-unknown-fields": true}
+			}
+			s.custom[gvk{APIVersion: group + "/" + name, Kind: kind}] = s.convertCRDSchema(copySchema(openAPI), true)
+		}
+	}
+}
+
+// convertCRDSchema adapts the Kubernetes extensions of a structural schema
+// to JSON Schema: int-or-string and nullable fields widen the type, and in
+// strict mode objects declaring their properties reject unknown fields
+func (s *schemaSet) convertCRDSchema(schema map[string]interface{}, root bool) map[string]interface{} {
+	if intOrString, _ := schema["x-kubernetes-int-or-string"].(bool); intOrString {
+		delete(schema, "type")
+		schema["anyOf"] = []interface{}{
+			map[string]interface{}{"type": "string"},
+			map[string]interface{}{"type": "integer"},
+		}
+	}
+	if nullable, _ := schema["nullable"].(bool); nullable {
+		if t, ok := schema["type"].(string); ok {
+			schema["type"] = []interface{}{t, "null"}
+		}
+	}
+
+	if props, ok := schema["properties"].(map[string]interface{}); ok {
+		for name, prop := range props {
+			if prop, ok := prop.(map[string]interface{}); ok {
+				props[name] = s.convertCRDSchema(prop, false)
+			}
+		}
+		// Every object carries apiVersion, kind and metadata, declared or not
+		if root {
+			for _, name := range []string{"apiVersion", "kind"} {
+				if _, ok := props[name]; !ok {
+					props[name] = map[string]interface{}{"type": "string"}
+				}
+			}
+			if _, ok := props["metadata"]; !ok {
+				props["metadata"] = map[string]interface{}{"type": "object"}
+			}
+		}
+	}
+	if items, ok := schema["items"].(map[string]interface{}); ok {
+		schema["items"] = s.convertCRDSchema(items, false)
+	}
+	if additional, ok := schema["additionalProperties"].(map[string]interface{}); ok {
+		schema["additionalProperties"] = s.convertCRDSchema(additional, false)
+	}
+	for _, key := range []string{"allOf", "anyOf", "oneOf"} {
+		if list, ok := schema[key].([]interface{}); ok {
+			for i, sub := range list {
+				if sub, ok := sub.(map[string]interface{}); ok {
+					list[i] = s.convertCRDSchema(sub, false)
+				}
+			}
+		}
+	}
+
+	if s.strict {
+		closeObject(schema)
+	}
+	return schema
+}
+
+// copySchema deep-copies a schema so that the manifest is left unchanged
+func copySchema(v map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(v))
+	for key, value := range v {
+		out[key] = copyValue(value)
+	}
+	return out
+}
+
+// copyValue deep-copies a decoded YAML value
+func copyValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		return copySchema(v)
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = copyValue(item)
+		}
+		return out
+	default:
+		return v
+	}
+}
@@ -0,0 +1,79 @@
+package validate
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Report describes the validation of the rendered manifests of a chart
+type Report struct {
+	ChartPath   string `json:"chartPath"`
+	KubeVersion string `json:"kubeVersion"`
+	Strict      bool   `json:"strict"`
+	// Validated counts the objects checked against a schema
+	Validated int       `json:"validated"`
+	Failures  []Failure `json:"failures,omitempty"`
+	// Skipped lists the custom resources without schema
+	Skipped []Object `json:"skipped,omitempty"`
+}
+
+// Object identifies a rendered manifest
+type Object struct {
+	// Subchart is the chain of subchart names, empty for the root chart
+	Subchart   string `json:"subchart,omitempty"`
+	Template   string `json:"template"`
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Name       string `json:"name,omitempty"`
+}
+
+// Failure is an object failing validation
+type Failure struct {
+	Object
+	Errors []string `json:"errors"`
+}
+
+// Write writes the report in the given format
+func (r *Report) Write(w io.Writer, format string) error {
+	if format == FormatJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	}
+	r.print(w)
+	return nil
+}
+
+// print writes the failures grouped by subchart and template
+func (r *Report) print(w io.Writer) {
+	subchart, template := "", ""
+	for i, f := range r.Failures {
+		if i == 0 || f.Subchart != subchart {
+			subchart, template = f.Subchart, ""
+			fmt.Fprintf(w, "%s:\n", subchartLabel(subchart))
+		}
+		if f.Template != template {
+			template = f.Template
+			fmt.Fprintf(w, "  %s\n", template)
+		}
+		fmt.Fprintf(w, "    %s %s %s:\n", f.APIVersion, f.Kind, f.Name)
+		for _, e := range f.Errors {
+			fmt.Fprintf(w, "      %s\n", e)
+		}
+	}
+
+	for _, s := range r.Skipped {
+		fmt.Fprintf(w, "Skipped %s %s %s in %s: no schema\n", s.APIVersion, s.Kind, s.Name, s.Template)
+	}
+
+	fmt.Fprintf(w, "Kubernetes %s: %d objects validated, %d invalid, %d skipped.\n", r.KubeVersion, r.Validated, len(r.Failures), len(r.Skipped))
+}
+
+// subchartLabel names a subchart chain in the text report
+func subchartLabel(subchart string) string {
+	if subchart == "" {
+		return "Root chart"
+	}
+	return "Subchart " + subchart
+}
@@ -0,0 +1,203 @@
+package validate
+
+import (
+	"bytes"
+	"compress/gzip"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/xeipuuv/gojsonschema"
+)
+
+//go:generate go run ./schemas/generate.go 1.28.15 1.29.15 1.30.14 1.31.9 1.32.6 1.33.1
+
+// bundled holds the OpenAPI definitions of the supported Kubernetes
+// versions, as written by schemas/generate.go
+//
+//go:embed schemas/*.json.gz
+var bundled embed.FS
+
+// KnownVersions returns the Kubernetes versions with bundled schemas, oldest first
+func KnownVersions() []string {
+	entries, _ := bundled.ReadDir("schemas")
+	var versions []string
+	for _, entry := range entries {
+		if name := entry.Name(); strings.HasSuffix(name, ".json.gz") {
+			versions = append(versions, strings.TrimSuffix(strings.TrimPrefix(name, "v"), ".json.gz"))
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return minorOf(versions[i]) < minorOf(versions[j])
+	})
+	return versions
+}
+
+// DefaultVersion returns the newest Kubernetes version with bundled schemas
+func DefaultVersion() string {
+	versions := KnownVersions()
+	return versions[len(versions)-1]
+}
+
+// normalizeVersion reduces "v1.30.2" or "1.30" to the bundled "1.30"
+func normalizeVersion(version string) (string, error) {
+	if version == "" {
+		return DefaultVersion(), nil
+	}
+	parts := strings.SplitN(strings.TrimPrefix(version, "v"), ".", 3)
+	if len(parts) >= 2 {
+		minor := parts[0] + "." + parts[1]
+		for _, known := range KnownVersions() {
+			if known == minor {
+				return minor, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no bundled schemas for Kubernetes version '%s' (known: %s)", version, strings.Join(KnownVersions(), ", "))
+}
+
+// minorOf returns the minor number of a "1.30" version, for sorting
+func minorOf(version string) int {
+	_, minor, _ := strings.Cut(version, ".")
+	n, _ := strconv.Atoi(minor)
+	return n
+}
+
+// gvk identifies the schema of an object by apiVersion and kind
+type gvk struct {
+	APIVersion string
+	Kind       string
+}
+
+func (k gvk) String() string {
+	return k.APIVersion + " " + k.Kind
+}
+
+// group returns the API group, empty for the core group
+func (k gvk) group() string {
+	if group, _, ok := strings.Cut(k.APIVersion, "/"); ok {
+		return group
+	}
+	return ""
+}
+
+// schemaSet compiles the schemas of one Kubernetes version on demand
+type schemaSet struct {
+	definitions map[string]interface{}
+	// byGVK maps every built-in kind to its definition name
+	byGVK map[gvk]string
+	// groups lists the API groups served by the version
+	groups map[string]bool
+	// custom holds the schemas of the CRDs shipped in the chart
+	custom   map[gvk]map[string]interface{}
+	strict   bool
+	compiled map[gvk]*gojsonschema.Schema
+}
+
+// loadSchemaSet reads the bundled definitions of a Kubernetes version
+func loadSchemaSet(version string, strict bool) (*schemaSet, error) {
+	data, err := bundled.ReadFile(path.Join("schemas", "v"+version+".json.gz"))
+	if err != nil {
+		return nil, err
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	raw, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, err
+	}
+	var spec struct {
+		Definitions map[string]interface{} `json:"definitions"`
+	}
+	if err := json.Unmarshal(raw, &spec); err != nil {
+		return nil, fmt.Errorf("failed to parse bundled schemas: %w", err)
+	}
+
+	s := &schemaSet{
+		definitions: spec.Definitions,
+		byGVK:       map[gvk]string{},
+		groups:      map[string]bool{},
+		custom:      map[gvk]map[string]interface{}{},
+		strict:      strict,
+		compiled:    map[gvk]*gojsonschema.Schema{},
+	}
+	for name, def := range s.definitions {
+		def, _ := def.(map[string]interface{})
+		kinds, _ := def["x-kubernetes-group-version-kind"].([]interface{})
+		for _, k := range kinds {
+			k, _ := k.(map[string]interface{})
+			group, _ := k["group"].(string)
+			version, _ := k["version"].(string)
+			kind, _ := k["kind"].(string)
+			apiVersion := version
+			if group != "" {
+				apiVersion = group + "/" + version
+			}
+			s.byGVK[gvk{APIVersion: apiVersion, Kind: kind}] = name
+			s.groups[group] = true
+		}
+		if strict {
+			closeObject(def)
+		}
+	}
+
+	// Manifests commonly use numbers for both: accept what the API server accepts
+	s.definitions["io.k8s.apimachinery.pkg.util.intstr.IntOrString"] = map[string]interface{}{"type": []interface{}{"string", "integer"}}
+	s.definitions["io.k8s.apimachinery.pkg.api.resource.Quantity"] = map[string]interface{}{"type": []interface{}{"string", "number"}}
+	return s, nil
+}
+
+// schema returns the compiled schema of a kind; ok is false when neither the
+// Kubernetes version nor a CRD of the chart defines it
+func (s *schemaSet) schema(k gvk) (*gojsonschema.Schema, bool, error) {
+	if compiled, ok := s.compiled[k]; ok {
+		return compiled, true, nil
+	}
+
+	var doc map[string]interface{}
+	if custom, ok := s.custom[k]; ok {
+		doc = custom
+	} else if name, ok := s.byGVK[k]; ok {
+		doc = map[string]interface{}{
+			"definitions": s.definitions,
+			"$ref":        "#/definitions/" + name,
+		}
+	} else {
+		return nil, false, nil
+	}
+
+	compiled, err := gojsonschema.NewSchema(gojsonschema.NewGoLoader(doc))
+	if err != nil {
+		return nil, true, fmt.Errorf("failed to compile the schema of %s: %w", k, err)
+	}
+	s.compiled[k] = compiled
+	return compiled, true, nil
+}
+
+// served reports whether the API group exists in the Kubernetes version,
+// so that a kind missing from it is an unsupported API rather than a
+// custom resource without schema
+func (s *schemaSet) served(k gvk) bool {
+	return s.groups[k.group()]
+}
+
+// closeObject rejects unknown fields in a schema declaring its properties
+func closeObject(schema map[string]interface{}) {
+	if _, ok := schema["properties"]; !ok {
+		return
+	}
+	if _, ok := schema["additionalProperties"]; ok {
+		return
+	}
+	if preserve, _ := schema["x-kubernetes-preserve-unknown-fields"].(bool); preserve {
+		return
+	}
+	schema["additionalProperties"] = false
+}
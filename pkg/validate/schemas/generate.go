@@ -0,0 +1,98 @@
+//go:build ignore
+
+// generate writes the bundled Kubernetes schemas: the OpenAPI definitions
+// of the k8s.io/kubernetes releases given as arguments, without their
+// descriptions, gzipped to schemas/v<major>.<minor>.json.gz.
+//
+// Usage (from pkg/validate): go run ./schemas/generate.go 1.33.1 ...
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, "usage: go run ./schemas/generate.go VERSION...")
+		os.Exit(1)
+	}
+	for _, version := range os.Args[1:] {
+		if err := generate(version); err != nil {
+			fmt.Fprintf(os.Stderr, "v%s: %v\n", version, err)
+			os.Exit(1)
+		}
+	}
+}
+
+// generate downloads a Kubernetes release through the module proxy and
+// writes its minimized definitions
+func generate(version string) error {
+	out, err := exec.Command("go", "mod", "download", "-json", "k8s.io/kubernetes@v"+version).Output()
+	if err != nil {
+		return fmt.Errorf("failed to download k8s.io/kubernetes: %w", err)
+	}
+	var module struct{ Dir string }
+	if err := json.Unmarshal(out, &module); err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(filepath.Join(module.Dir, "api", "openapi-spec", "swagger.json"))
+	if err != nil {
+		return err
+	}
+	var spec struct {
+		Definitions map[string]interface{} `json:"definitions"`
+	}
+	if err := json.Unmarshal(data, &spec); err != nil {
+		return err
+	}
+
+	minimized, err := json.Marshal(map[string]interface{}{"definitions": strip(spec.Definitions)})
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	zw, _ := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	if _, err := zw.Write(minimized); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+
+	minor := strings.Join(strings.SplitN(version, ".", 3)[:2], ".")
+	name := filepath.Join("schemas", "v"+minor+".json.gz")
+	fmt.Printf("%s: %d definitions from k8s.io/kubernetes@v%s\n", name, len(spec.Definitions), version)
+	return os.WriteFile(name, buf.Bytes(), 0o644)
+}
+
+// strip removes the descriptions, which make up most of the specification
+func strip(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		out := map[string]interface{}{}
+		for key, value := range v {
+			if key == "description" {
+				if _, isString := value.(string); isString {
+					continue
+				}
+			}
+			out[key] = strip(value)
+		}
+		return out
+	case []interface{}:
+		for i := range v {
+			v[i] = strip(v[i])
+		}
+		return v
+	default:
+		return v
+	}
+}
@@ -0,0 +1,190 @@
+package validate
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/harness/helm-optimize/pkg/common"
+	"github.com/harness/helm-optimize/pkg/render"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// Output formats of the validation report
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// KnownFormats lists the supported output formats
+var KnownFormats = []string{FormatText, FormatJSON}
+
+// Options defines the parameters for validating the rendered manifests of a chart
+type Options struct {
+	ChartPath  string
+	ValueFiles []string
+	// KubeVersion selects the bundled schemas, e.g. "1.30"; the newest when empty
+	KubeVersion string
+	// Strict rejects unknown fields and objects without a schema
+	Strict bool
+	// Format is the output format: text or json
+	Format string
+}
+
+// Run validates the chart and writes the report to stdout. The error wraps
+// common.ErrVerificationFailed when a manifest is invalid.
+func Run(opts Options) error {
+	if opts.Format == "" {
+		opts.Format = FormatText
+	}
+	if !contains(KnownFormats, opts.Format) {
+		return fmt.Errorf("unknown output format '%s' (known: %s)", opts.Format, strings.Join(KnownFormats, ", "))
+	}
+
+	report, err := Validate(opts)
+	if err != nil {
+		return err
+	}
+	if err := report.Write(os.Stdout, opts.Format); err != nil {
+		return err
+	}
+
+	if len(report.Failures) > 0 {
+		return fmt.Errorf("%w: %d of %d objects are invalid for Kubernetes %s", common.ErrVerificationFailed, len(report.Failures), report.Validated, report.KubeVersion)
+	}
+	return nil
+}
+
+// Validate renders the chart for the selected Kubernetes version and
+// validates every manifest, including the CRDs shipped in crds/ directories,
+// against the bundled schemas and the schemas of those CRDs
+func Validate(opts Options) (*Report, error) {
+	version, err := normalizeVersion(opts.KubeVersion)
+	if err != nil {
+		return nil, err
+	}
+	schemas, err := loadSchemaSet(version, opts.Strict)
+	if err != nil {
+		return nil, err
+	}
+
+	c, rendered, err := render.Load(render.Options{
+		ChartPath:   opts.ChartPath,
+		ValueFiles:  opts.ValueFiles,
+		KubeVersion: "v" + version + ".0",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// CRDs are installed from crds/ without templating, before the templates
+	crdFiles := map[string]string{}
+	for _, crd := range c.CRDObjects() {
+		crdFiles[crd.Filename] = string(crd.File.Data)
+	}
+	crds, err := render.Split(crdFiles)
+	if err != nil {
+		return nil, err
+	}
+	templates, err := render.Split(rendered)
+	if err != nil {
+		return nil, err
+	}
+	manifests := append(crds, templates...)
+	schemas.addCRDs(manifests)
+
+	report := &Report{ChartPath: opts.ChartPath, KubeVersion: version, Strict: opts.Strict}
+	for _, m := range manifests {
+		obj := Object{
+			Subchart:   m.Subchart,
+			Template:   m.Template,
+			APIVersion: m.APIVersion(),
+			Kind:       m.Kind(),
+			Name:       m.Name(),
+		}
+		errs, skipped, err := validateManifest(schemas, m, version, opts.Strict)
+		if err != nil {
+			return nil, err
+		}
+		if skipped {
+			report.Skipped = append(report.Skipped, obj)
+			continue
+		}
+		report.Validated++
+		if len(errs) > 0 {
+			report.Failures = append(report.Failures, Failure{Object: obj, Errors: errs})
+		}
+	}
+
+	sort.SliceStable(report.Failures, func(i, j int) bool {
+		return report.Failures[i].Subchart < report.Failures[j].Subchart
+	})
+	return report, nil
+}
+
+// validateManifest returns the validation errors of a manifest; skipped is
+// true for a custom resource without schema outside strict mode
+func validateManifest(schemas *schemaSet, m render.Manifest, version string, strict bool) ([]string, bool, error) {
+	k := gvk{APIVersion: m.APIVersion(), Kind: m.Kind()}
+	if k.APIVersion == "" || k.Kind == "" {
+		return []string{"apiVersion and kind are required"}, false, nil
+	}
+
+	schema, ok, err := schemas.schema(k)
+	if err != nil {
+		return nil, false, err
+	}
+	if !ok {
+		switch {
+		case schemas.served(k):
+			return []string{fmt.Sprintf("%s is not served by Kubernetes %s", k, version)}, false, nil
+		case strict:
+			return []string{fmt.Sprintf("no schema for %s: neither built in nor defined by a CRD of the chart", k)}, false, nil
+		default:
+			return nil, true, nil
+		}
+	}
+
+	result, err := schema.Validate(gojsonschema.NewGoLoader(dropNulls(m.Object)))
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to validate %s in %s: %w", k, m.Template, err)
+	}
+	var errs []string
+	for _, e := range result.Errors() {
+		errs = append(errs, fmt.Sprintf("%s: %s", e.Field(), e.Description()))
+	}
+	return errs, false, nil
+}
+
+// dropNulls removes null fields, which the API server treats as unset
+func dropNulls(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			if value != nil {
+				out[key] = dropNulls(value)
+			}
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = dropNulls(item)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// contains reports whether the list holds the value
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
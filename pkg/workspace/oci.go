@@ -0,0 +1,92 @@
+package workspace
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/registry"
+)
+
+// ociIndex is the subset of an OCI image layout index.json used here
+type ociIndex struct {
+	Manifests []ociDescriptor `json:"manifests"`
+}
+
+// ociManifest is the subset of an OCI image manifest used here
+type ociManifest struct {
+	Config ociDescriptor   `json:"config"`
+	Layers []ociDescriptor `json:"layers"`
+}
+
+// ociDescriptor references a blob in the layout
+type ociDescriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// refNameAnnotation holds the tag of a manifest in an OCI layout index
+const refNameAnnotation = "org.opencontainers.image.ref.name"
+
+// isOCILayout reports whether the directory is an OCI image layout
+func isOCILayout(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, "oci-layout"))
+	return err == nil
+}
+
+// openOCILayout extracts the Helm chart stored in a local OCI image layout.
+// The layout must hold exactly one chart manifest.
+func openOCILayout(dir string) (*Workspace, error) {
+	var index ociIndex
+	if err := readJSON(filepath.Join(dir, "index.json"), &index); err != nil {
+		return nil, fmt.Errorf("failed to read OCI layout index: %w", err)
+	}
+
+	var charts []ociManifest
+	var tags []string
+	for _, desc := range index.Manifests {
+		var manifest ociManifest
+		if err := readJSON(blobPath(dir, desc.Digest), &manifest); err != nil {
+			return nil, fmt.Errorf("failed to read manifest %s: %w", desc.Digest, err)
+		}
+		if manifest.Config.MediaType != registry.ConfigMediaType {
+			continue
+		}
+		charts = append(charts, manifest)
+		tags = append(tags, desc.Annotations[refNameAnnotation])
+	}
+
+	switch len(charts) {
+	case 0:
+		return nil, fmt.Errorf("OCI layout '%s' does not contain a Helm chart", dir)
+	case 1:
+	default:
+		return nil, fmt.Errorf("OCI layout '%s' contains %d charts (%s), expected one", dir, len(charts), strings.Join(tags, ", "))
+	}
+
+	for _, layer := range charts[0].Layers {
+		if layer.MediaType == registry.ChartLayerMediaType || layer.MediaType == registry.LegacyChartLayerMediaType {
+			return openArchive(dir, blobPath(dir, layer.Digest), KindOCILayout)
+		}
+	}
+
+	return nil, fmt.Errorf("OCI layout '%s' has no chart content layer", dir)
+}
+
+// blobPath returns the location of a blob in an OCI layout
+func blobPath(dir, digest string) string {
+	algorithm, encoded, _ := strings.Cut(digest, ":")
+	return filepath.Join(dir, "blobs", algorithm, encoded)
+}
+
+// readJSON decodes a JSON file
+func readJSON(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,79 @@
+package workspace
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/registry"
+)
+
+// PushResult describes a chart uploaded to an OCI registry
+type PushResult struct {
+	// Ref is the full reference the chart was pushed to, e.g. host/repo/chart:1.2.3
+	Ref string `json:"ref"`
+	// Digest is the digest of the pushed manifest
+	Digest string `json:"digest"`
+}
+
+// PushOptions defines how a chart archive is pushed
+type PushOptions struct {
+	// Remote is the target repository, e.g. oci://host/repo
+	Remote string
+	// PlainHTTP talks to the registry over HTTP instead of HTTPS
+	PlainHTTP bool
+	// Provenance is the provenance file pushed along with the chart, if any
+	Provenance string
+}
+
+// Push uploads a chart archive to an OCI registry the way 'helm push' does:
+// the chart is stored as <remote>/<name>:<version>. Credentials are read from
+// Helm's registry configuration ('helm registry login').
+func Push(archive string, opts PushOptions) (*PushResult, error) {
+	if !strings.HasPrefix(opts.Remote, registry.OCIScheme+"://") {
+		return nil, fmt.Errorf("push target '%s' must start with %s://", opts.Remote, registry.OCIScheme)
+	}
+
+	data, err := os.ReadFile(archive)
+	if err != nil {
+		return nil, err
+	}
+	c, err := loader.LoadArchive(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load chart archive: %w", err)
+	}
+
+	clientOpts := []registry.ClientOption{
+		registry.ClientOptCredentialsFile(cli.New().RegistryConfig),
+		registry.ClientOptWriter(io.Discard),
+	}
+	if opts.PlainHTTP {
+		clientOpts = append(clientOpts, registry.ClientOptPlainHTTP())
+	}
+	client, err := registry.NewClient(clientOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create registry client: %w", err)
+	}
+
+	remote := strings.TrimSuffix(strings.TrimPrefix(opts.Remote, registry.OCIScheme+"://"), "/")
+	ref := fmt.Sprintf("%s/%s:%s", remote, c.Name(), c.Metadata.Version)
+
+	var pushOpts []registry.PushOption
+	if opts.Provenance != "" {
+		prov, err := os.ReadFile(opts.Provenance)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read provenance file: %w", err)
+		}
+		pushOpts = append(pushOpts, registry.PushOptProvData(prov))
+	}
+
+	result, err := client.Push(data, ref, pushOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to push to %s: %w", ref, err)
+	}
+	return &PushResult{Ref: result.Ref, Digest: result.Manifest.Digest}, nil
+}
@@ -0,0 +1,116 @@
+package workspace
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/harness/helm-optimize/pkg/common"
+	"golang.org/x/term"
+	"helm.sh/helm/v3/pkg/provenance"
+)
+
+// PassphraseEnv holds the passphrase of an encrypted signing key, as in 'helm package --sign'
+const PassphraseEnv = "HELM_KEY_PASSPHRASE"
+
+// SignOptions defines how a chart archive is signed
+type SignOptions struct {
+	// Key is the name of the signing key in the keyring
+	Key string
+	// Keyring is the path of the secret keyring
+	Keyring string
+	// PassphraseFile holds the key passphrase; "-" reads it from stdin
+	PassphraseFile string
+}
+
+// Signer writes Helm provenance files with an unlocked private key
+type Signer struct {
+	signatory *provenance.Signatory
+}
+
+// NewSigner loads the signing key from the keyring. An encrypted key is
+// unlocked with the passphrase file, the HELM_KEY_PASSPHRASE environment
+// variable or an interactive prompt, in that order.
+func NewSigner(opts SignOptions) (*Signer, error) {
+	if opts.Key == "" || opts.Keyring == "" {
+		return nil, fmt.Errorf("signing requires a key name and a keyring")
+	}
+
+	signatory, err := provenance.NewFromKeyring(opts.Keyring, opts.Key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load signing key: %w", err)
+	}
+	if err := signatory.DecryptKey(passphraseFetcher(opts.PassphraseFile)); err != nil {
+		return nil, fmt.Errorf("failed to decrypt signing key %q: %w", opts.Key, err)
+	}
+	return &Signer{signatory: signatory}, nil
+}
+
+// Sign writes a provenance file next to the archive and returns its path
+func (s *Signer) Sign(archive string) (string, error) {
+	sig, err := s.signatory.ClearSign(archive)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign %s: %w", archive, err)
+	}
+
+	prov := archive + ".prov"
+	if err := os.WriteFile(prov, []byte(sig), 0644); err != nil {
+		return "", fmt.Errorf("failed to write provenance file: %w", err)
+	}
+	return prov, nil
+}
+
+// Verify checks the provenance file next to the archive against a public keyring
+func Verify(archive, keyring string) (*provenance.Verification, error) {
+	prov := archive + ".prov"
+	if _, err := os.Stat(prov); err != nil {
+		return nil, fmt.Errorf("%w: provenance file '%s' not found", common.ErrVerificationFailed, prov)
+	}
+
+	signatory, err := provenance.NewFromKeyring(keyring, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to load keyring: %w", err)
+	}
+	verification, err := signatory.Verify(archive, prov)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", common.ErrVerificationFailed, err)
+	}
+	return verification, nil
+}
+
+// passphraseFetcher returns the source of the signing key passphrase
+func passphraseFetcher(passphraseFile string) provenance.PassphraseFetcher {
+	return func(name string) ([]byte, error) {
+		switch {
+		case passphraseFile == "-":
+			return readPassphrase(os.Stdin)
+		case passphraseFile != "":
+			f, err := os.Open(passphraseFile)
+			if err != nil {
+				return nil, err
+			}
+			defer f.Close()
+			return readPassphrase(f)
+		case os.Getenv(PassphraseEnv) != "":
+			return []byte(os.Getenv(PassphraseEnv)), nil
+		}
+
+		if !term.IsTerminal(int(os.Stdin.Fd())) {
+			return nil, fmt.Errorf("key %q is encrypted: provide a passphrase file or set %s", name, PassphraseEnv)
+		}
+		fmt.Fprintf(os.Stderr, "Password for key %q: ", name)
+		pw, err := term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Fprintln(os.Stderr)
+		return pw, err
+	}
+}
+
+// readPassphrase reads the first line of a passphrase file
+func readPassphrase(f *os.File) ([]byte, error) {
+	line, err := bufio.NewReader(f).ReadString('\n')
+	if err != nil && line == "" {
+		return nil, fmt.Errorf("failed to read passphrase: %w", err)
+	}
+	return []byte(strings.TrimRight(line, "\r\n")), nil
+}
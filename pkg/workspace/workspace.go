@@ -0,0 +1,169 @@
+package workspace
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/harness/helm-optimize/pkg/common"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+)
+
+// Kind describes what a chart source points at
+type Kind string
+
+const (
+	// KindDirectory is an unpacked chart directory, optimized in place
+	KindDirectory Kind = "directory"
+	// KindArchive is a packaged chart (.tgz)
+	KindArchive Kind = "archive"
+	// KindOCILayout is a local OCI image layout directory holding a chart artifact
+	KindOCILayout Kind = "oci-layout"
+)
+
+// Workspace is a chart made available as an unpacked directory.
+// Archives and OCI layouts are extracted into a temporary directory that is
+// removed by Close; directories are used in place.
+type Workspace struct {
+	// Source is the path given by the user
+	Source string
+	// Kind is the type of the source
+	Kind Kind
+	// ChartPath is the unpacked chart directory to operate on
+	ChartPath string
+	// tempDir is the temporary extraction directory, empty for directories
+	tempDir string
+}
+
+// Open prepares a workspace for the chart source. Callers must Close it.
+func Open(source string) (*Workspace, error) {
+	info, err := os.Stat(source)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("%w: chart path '%s' does not exist", common.ErrChartNotFound, source)
+		}
+		return nil, err
+	}
+
+	if info.IsDir() {
+		if isOCILayout(source) {
+			return openOCILayout(source)
+		}
+		return &Workspace{Source: source, Kind: KindDirectory, ChartPath: source}, nil
+	}
+
+	if IsArchive(source) {
+		return openArchive(source, source, KindArchive)
+	}
+
+	return nil, fmt.Errorf("'%s' is neither a chart directory, a chart archive nor an OCI layout", source)
+}
+
+// IsArchive reports whether the path names a packaged chart
+func IsArchive(path string) bool {
+	return strings.HasSuffix(path, ".tgz") || strings.HasSuffix(path, ".tar.gz")
+}
+
+// IsTemporary reports whether the chart was extracted to a temporary directory
+func (w *Workspace) IsTemporary() bool {
+	return w.tempDir != ""
+}
+
+// DefaultOutputDir returns where a repackaged chart is written when no output
+// directory is given: next to the source. Use CheckOutput to make sure the
+// source archive is not overwritten.
+func (w *Workspace) DefaultOutputDir() string {
+	return filepath.Dir(filepath.Clean(w.Source))
+}
+
+// CheckOutput refuses an output directory where packaging the chart would
+// overwrite the source archive, as an archive repackaged next to itself keeps
+// its <name>-<version>.tgz file name
+func (w *Workspace) CheckOutput(outputDir string) error {
+	if w.Kind != KindArchive {
+		return nil
+	}
+	metadata, err := chartutil.LoadChartfile(filepath.Join(w.ChartPath, "Chart.yaml"))
+	if err != nil {
+		return fmt.Errorf("%w: failed to load Chart.yaml: %w", common.ErrInvalidChart, err)
+	}
+	target := filepath.Join(outputDir, fmt.Sprintf("%s-%s.tgz", metadata.Name, metadata.Version))
+
+	same := false
+	if sourceInfo, err := os.Stat(w.Source); err == nil {
+		if targetInfo, err := os.Stat(target); err == nil {
+			same = os.SameFile(sourceInfo, targetInfo)
+		}
+	}
+	if !same {
+		absSource, err := filepath.Abs(w.Source)
+		if err != nil {
+			return err
+		}
+		absTarget, err := filepath.Abs(target)
+		if err != nil {
+			return err
+		}
+		same = absSource == absTarget
+	}
+	if same {
+		return fmt.Errorf("%w: packaging the optimized chart to %s would overwrite the source archive, choose another directory with --output", common.ErrUnsafePath, target)
+	}
+	return nil
+}
+
+// Close removes the temporary extraction directory, if any
+func (w *Workspace) Close() error {
+	if w.tempDir == "" {
+		return nil
+	}
+	return os.RemoveAll(w.tempDir)
+}
+
+// openArchive extracts a chart archive into a temporary directory
+func openArchive(source, archive string, kind Kind) (*Workspace, error) {
+	tempDir, err := os.MkdirTemp("", "helm-optimize-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create workspace: %w", err)
+	}
+
+	if err := chartutil.ExpandFile(tempDir, archive); err != nil {
+		os.RemoveAll(tempDir)
+		return nil, fmt.Errorf("failed to extract '%s': %w", source, err)
+	}
+
+	// The archive holds a single top level chart directory
+	entries, err := os.ReadDir(tempDir)
+	if err != nil {
+		os.RemoveAll(tempDir)
+		return nil, err
+	}
+	if len(entries) != 1 || !entries[0].IsDir() {
+		os.RemoveAll(tempDir)
+		return nil, fmt.Errorf("archive '%s' does not contain a single chart directory", source)
+	}
+
+	return &Workspace{
+		Source:    source,
+		Kind:      kind,
+		ChartPath: filepath.Join(tempDir, entries[0].Name()),
+		tempDir:   tempDir,
+	}, nil
+}
+
+// Package writes the chart at chartPath as a versioned archive into outputDir
+// and returns the archive path
+func Package(chartPath, outputDir string) (string, error) {
+	c, err := loader.Load(chartPath)
+	if err != nil {
+		return "", fmt.Errorf("%w: failed to load chart: %w", common.ErrInvalidChart, err)
+	}
+
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	return chartutil.Save(c, outputDir)
+}